@@ -0,0 +1,160 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavcodec
+
+#include <string.h>
+
+#include "libavformat/avformat.h"
+#include "libavcodec/avcodec.h"
+
+static int gmf_new_attached_pic(AVPacket *pkt, const void *data, int size) {
+	int ret;
+
+	if ((ret = av_new_packet(pkt, size)) < 0) {
+		return ret;
+	}
+
+	memcpy(pkt->data, data, size);
+
+	return 0;
+}
+
+// the packet data is moved to the stream, it's freed with the stream by avformat_free_context
+static void gmf_set_attached_pic(AVStream *st, AVPacket *pkt) {
+	av_packet_unref(&st->attached_pic);
+	av_packet_move_ref(&st->attached_pic, pkt);
+
+	st->attached_pic.stream_index = st->index;
+	st->attached_pic.flags       |= AV_PKT_FLAG_KEY;
+	st->attached_pic.pts          = 0;
+	st->attached_pic.dts          = 0;
+
+	st->disposition |= AV_DISPOSITION_ATTACHED_PIC;
+}
+
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"unsafe"
+)
+
+// AttachedPicture is a picture of the attached_pic stream, e.g. cover art of MP3 or M4A.
+type AttachedPicture struct {
+	Stream *Stream
+	Packet *Packet
+}
+
+// CodecId returns codec of the picture, e.g. AV_CODEC_ID_MJPEG or AV_CODEC_ID_PNG.
+func (p *AttachedPicture) CodecId() int {
+	return int(p.Stream.avStream.codecpar.codec_id)
+}
+
+// Data returns a copy of the encoded image.
+func (p *AttachedPicture) Data() []byte {
+	return p.Packet.Data()
+}
+
+func (p *AttachedPicture) Free() {
+	p.Packet.Free()
+}
+
+// AttachedPictures returns pictures of all the attached_pic streams of the input.
+// Packets are references to the stream pictures, they should be freed by caller.
+func (this *FmtCtx) AttachedPictures() []*AttachedPicture {
+	result := make([]*AttachedPicture, 0)
+
+	for i := 0; i < this.StreamsCnt(); i++ {
+		st, err := this.GetStream(i)
+		if err != nil || !st.IsAttachedPic() || st.avStream.attached_pic.size <= 0 {
+			continue
+		}
+
+		pkt := NewPacket()
+		if ret := C.av_packet_ref(&pkt.avPacket, &st.avStream.attached_pic); ret < 0 {
+			pkt.Free()
+			continue
+		}
+
+		result = append(result, &AttachedPicture{Stream: st, Packet: pkt})
+	}
+
+	return result
+}
+
+// AddAttachedPicture adds attached_pic stream with the JPEG or PNG image to
+// the output context, e.g. cover art. It must be called before WriteHeader,
+// the picture is written right after the header.
+func (this *FmtCtx) AddAttachedPicture(data []byte) (*Stream, error) {
+	var codecId int
+
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		codecId = AV_CODEC_ID_PNG
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		codecId = AV_CODEC_ID_MJPEG
+	default:
+		return nil, errors.New("Unsupported attached picture format, only JPEG and PNG are supported")
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode attached picture: %s", err))
+	}
+
+	// the picture is copied before the stream is created, so no stream is left without it on error
+	pkt := NewPacket()
+	if ret := C.gmf_new_attached_pic(&pkt.avPacket, unsafe.Pointer(&data[0]), C.int(len(data))); ret < 0 {
+		pkt.Free()
+		return nil, AvError(int(ret))
+	}
+
+	st := this.NewStream(nil)
+	if st == nil {
+		pkt.Free()
+		return nil, errors.New("Unable to create attached picture stream")
+	}
+
+	par := st.avStream.codecpar
+	par.codec_type = C.AVMEDIA_TYPE_VIDEO
+	par.codec_id = uint32(codecId)
+	par.width = C.int(cfg.Width)
+	par.height = C.int(cfg.Height)
+
+	st.SetTimeBase(AVR{Num: 1, Den: 90000})
+
+	C.gmf_set_attached_pic(st.avStream, &pkt.avPacket)
+
+	return st, nil
+}
+
+// writeAttachedPictures writes pictures added by AddAttachedPicture.
+func (this *FmtCtx) writeAttachedPictures() error {
+	for _, st := range this.streams {
+		if st == nil || !st.IsAttachedPic() || st.avStream.attached_pic.size <= 0 {
+			continue
+		}
+
+		pkt := NewPacket()
+		if ret := C.av_packet_ref(&pkt.avPacket, &st.avStream.attached_pic); ret < 0 {
+			pkt.Free()
+			return AvError(int(ret))
+		}
+
+		err := this.writePacket(pkt)
+		pkt.Free()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package gmf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func coverPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		img.Set(x, x%48, color.RGBA{R: 255, A: 255})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestAttachedPictures(t *testing.T) {
+	cover := coverPNG(t)
+
+	f, err := ioutil.TempFile("", "gmf-cover-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	outputCtx, err := NewOutputCtxToWriter(f, "mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := outputCtx.AddAttachedPicture([]byte("not an image")); err == nil {
+		t.Fatalf("Expected error adding invalid picture\n")
+	}
+
+	prepare := func(inputCtx *FmtCtx, ist, ost *Stream) {
+		st, err := outputCtx.AddAttachedPicture(cover)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !st.IsAttachedPic() {
+			t.Fatalf("Expected attached_pic disposition, %s got\n", st.Disposition())
+		}
+	}
+
+	if err := remuxSampleWith(t, outputCtx, prepare); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	pics := inputCtx.AttachedPictures()
+	if len(pics) != 1 {
+		t.Fatalf("Expected 1 attached picture, %d got\n", len(pics))
+	}
+	defer pics[0].Free()
+
+	if pics[0].CodecId() != AV_CODEC_ID_PNG || !bytes.Equal(pics[0].Data(), cover) {
+		t.Fatalf("Expected the same PNG cover, codec %d, %d bytes got\n", pics[0].CodecId(), len(pics[0].Data()))
+	}
+}
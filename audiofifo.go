@@ -49,6 +49,7 @@ type AVAudioFifo struct {
 	avAudioFifo  *C.struct_AVAudioFifo
 	sampleFormat int32
 	channels     int
+	chLayout     *ChannelLayout
 }
 
 func NewAVAudioFifo(sampleFormat int32, channels int, nb_samples int) *AVAudioFifo {
@@ -65,6 +66,23 @@ func NewAVAudioFifo(sampleFormat int32, channels int, nb_samples int) *AVAudioFi
 	}
 }
 
+// NewAVAudioFifoWithLayout is the same as NewAVAudioFifo, frames returned by Read have the layout.
+func NewAVAudioFifoWithLayout(sampleFormat int32, layout *ChannelLayout, nb_samples int) *AVAudioFifo {
+	if layout == nil {
+		return nil
+	}
+
+	chLayout, err := layout.Copy()
+	if err != nil {
+		return nil
+	}
+
+	fifo := NewAVAudioFifo(sampleFormat, chLayout.NbChannels(), nb_samples)
+	fifo.chLayout = chLayout
+
+	return fifo
+}
+
 func (this *AVAudioFifo) SamplesToRead() int {
 	return int(C.av_audio_fifo_size(this.avAudioFifo))
 }
@@ -85,7 +103,16 @@ func (this *AVAudioFifo) Read(sampleCount int) *Frame {
 		size = sampleCount
 	}
 
-	frame, err := NewAudioFrame(this.sampleFormat, this.channels, size)
+	var (
+		frame *Frame
+		err   error
+	)
+
+	if this.chLayout != nil {
+		frame, err = NewAudioFrameWithLayout(this.sampleFormat, this.chLayout, size)
+	} else {
+		frame, err = NewAudioFrame(this.sampleFormat, this.channels, size)
+	}
 	if frame == nil || err != nil {
 		return nil
 	}
@@ -100,4 +127,9 @@ func (this *AVAudioFifo) Read(sampleCount int) *Frame {
 
 func (this *AVAudioFifo) Free() {
 	C.av_audio_fifo_free(this.avAudioFifo)
+
+	if this.chLayout != nil {
+		this.chLayout.Free()
+		this.chLayout = nil
+	}
 }
@@ -52,19 +52,18 @@ type AVIOHandlers struct {
 	Seek        func(int64, int) int64
 }
 
-// Global map of AVIOHandlers
-// one handlers struct per format context. Using ctx.avCtx pointer address as a key.
-var handlersMap map[uintptr]*AVIOHandlers
-
 type AVIOContext struct {
 	avAVIOContext *C.AVIOContext
 	// avAVIOContext *C.struct_AVIOContext
-	handlerKey uintptr
+	// opaque is a key of the handlers, one handlers struct per AVIOContext,
+	// so several contexts may be bound to the same format context.
+	opaque unsafe.Pointer
 	CgoMemoryManage
 	buffer *C.uchar
 }
 
 // AVIOContext constructor. Use it only if You need custom IO behaviour!
+// It's safe to create and use AVIOContexts from multiple goroutines.
 func NewAVIOContext(ctx *FmtCtx, handlers *AVIOHandlers, size ...int) (*AVIOContext, error) {
 	this := &AVIOContext{}
 
@@ -83,37 +82,37 @@ func NewAVIOContext(ctx *FmtCtx, handlers *AVIOHandlers, size ...int) (*AVIOCont
 	// we have to explicitly set it to nil, to force library using default handlers
 	var ptrRead, ptrWrite, ptrSeek *[0]byte = nil, nil, nil
 
+	var flag int = 0
+
 	if handlers != nil {
-		if handlersMap == nil {
-			handlersMap = make(map[uintptr]*AVIOHandlers)
+		var err error
+		if this.opaque, err = newHandle(handlers); err != nil {
+			C.av_free(unsafe.Pointer(this.buffer))
+			return nil, err
 		}
 
-		handlersMap[uintptr(unsafe.Pointer(ctx.avCtx))] = handlers
-		this.handlerKey = uintptr(unsafe.Pointer(ctx.avCtx))
-	}
-
-	var flag int = 0
-
-	if handlers.ReadPacket != nil {
-		ptrRead = (*[0]byte)(C.readCallBack)
-		flag = 0
-	}
+		if handlers.ReadPacket != nil {
+			ptrRead = (*[0]byte)(C.readCallBack)
+			flag = 0
+		}
 
-	if handlers.WritePacket != nil {
-		ptrWrite = (*[0]byte)(C.writeCallBack)
-		flag = AVIO_FLAG_WRITE
-	}
+		if handlers.WritePacket != nil {
+			ptrWrite = (*[0]byte)(C.writeCallBack)
+			flag = AVIO_FLAG_WRITE
+		}
 
-	if handlers.Seek != nil {
-		ptrSeek = (*[0]byte)(C.seekCallBack)
-	}
+		if handlers.Seek != nil {
+			ptrSeek = (*[0]byte)(C.seekCallBack)
+		}
 
-	if handlers.ReadPacket != nil && handlers.WritePacket != nil {
-		flag = AVIO_FLAG_READ_WRITE
+		if handlers.ReadPacket != nil && handlers.WritePacket != nil {
+			flag = AVIO_FLAG_READ_WRITE
+		}
 	}
 
-	if this.avAVIOContext = C.avio_alloc_context(this.buffer, C.int(bufferSize), C.int(flag), unsafe.Pointer(ctx.avCtx), ptrRead, ptrWrite, ptrSeek); this.avAVIOContext == nil {
-		C.av_free(unsafe.Pointer(this.avAVIOContext.buffer))
+	if this.avAVIOContext = C.avio_alloc_context(this.buffer, C.int(bufferSize), C.int(flag), this.opaque, ptrRead, ptrWrite, ptrSeek); this.avAVIOContext == nil {
+		C.av_free(unsafe.Pointer(this.buffer))
+		deleteHandle(this.opaque)
 		return nil, errors.New("unable to initialize avio context")
 	}
 
@@ -123,21 +122,29 @@ func NewAVIOContext(ctx *FmtCtx, handlers *AVIOHandlers, size ...int) (*AVIOCont
 }
 
 func (this *AVIOContext) Free() {
-	delete(handlersMap, this.handlerKey)
+	deleteHandle(this.opaque)
+	this.opaque = nil
+
 	C.av_free(unsafe.Pointer(this.avAVIOContext.buffer))
 	C.av_free(unsafe.Pointer(this.avAVIOContext))
 }
 
+func lookupAVIOHandlers(opaque unsafe.Pointer) *AVIOHandlers {
+	handlers, found := handleValue(opaque).(*AVIOHandlers)
+	if !found {
+		panic(fmt.Sprintf("No handlers instance found, according pointer: %v", opaque))
+	}
+
+	return handlers
+}
+
 func (this *AVIOContext) Flush() {
 	C.avio_flush(this.avAVIOContext)
 }
 
 //export readCallBack
 func readCallBack(opaque unsafe.Pointer, buf *C.uint8_t, buf_size C.int) C.int {
-	handlers, found := handlersMap[uintptr(opaque)]
-	if !found {
-		panic(fmt.Sprintf("No handlers instance found, according pointer: %v", opaque))
-	}
+	handlers := lookupAVIOHandlers(opaque)
 
 	if handlers.ReadPacket == nil {
 		panic("No reader handler initialized")
@@ -153,10 +160,7 @@ func readCallBack(opaque unsafe.Pointer, buf *C.uint8_t, buf_size C.int) C.int {
 
 //export writeCallBack
 func writeCallBack(opaque unsafe.Pointer, buf *C.uint8_t, buf_size C.int) C.int {
-	handlers, found := handlersMap[uintptr(opaque)]
-	if !found {
-		panic(fmt.Sprintf("No handlers instance found, according pointer: %v", opaque))
-	}
+	handlers := lookupAVIOHandlers(opaque)
 
 	if handlers.WritePacket == nil {
 		panic("No writer handler initialized.")
@@ -167,10 +171,7 @@ func writeCallBack(opaque unsafe.Pointer, buf *C.uint8_t, buf_size C.int) C.int
 
 //export seekCallBack
 func seekCallBack(opaque unsafe.Pointer, offset C.int64_t, whence C.int) C.int64_t {
-	handlers, found := handlersMap[uintptr(opaque)]
-	if !found {
-		panic(fmt.Sprintf("No handlers instance found, according pointer: %v", opaque))
-	}
+	handlers := lookupAVIOHandlers(opaque)
 
 	if handlers.Seek == nil {
 		panic("No seek handler initialized.")
@@ -0,0 +1,208 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavcodec
+
+#include <stdlib.h>
+#include "libavcodec/avcodec.h"
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// BitstreamFilter is a wrapper of AVBSFContext, e.g. "h264_mp4toannexb"
+// for remuxing H.264 from MP4 into MPEG-TS.
+type BitstreamFilter struct {
+	avBSFCtx *C.struct_AVBSFContext
+	options  []*Option
+	CgoMemoryManage
+}
+
+// NewBitstreamFilter creates and initializes bitstream filter by name,
+// for the input stream with codec parameters cp and time base timeBase.
+// Options are set by SetOption before initialization, e.g.:
+//
+//	NewBitstreamFilter("hevc_metadata", ist.GetCodecPar(), ist.TimeBase().AVR(), &Option{Key: "level", Val: 120})
+func NewBitstreamFilter(name string, cp *CodecParameters, timeBase AVR, options ...*Option) (*BitstreamFilter, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	filter := C.av_bsf_get_by_name(cname)
+	if filter == nil {
+		return nil, newAVError(AVERROR_BSF_NOT_FOUND, "Unable to find bitstream filter", name)
+	}
+
+	this := &BitstreamFilter{options: options}
+
+	if ret := int(C.av_bsf_alloc(filter, &this.avBSFCtx)); ret < 0 {
+		return nil, newAVError(ret, "Unable to allocate bitstream filter", name)
+	}
+
+	if cp != nil && cp.avCodecParameters != nil {
+		if ret := int(C.avcodec_parameters_copy(this.avBSFCtx.par_in, cp.avCodecParameters)); ret < 0 {
+			this.Free()
+			return nil, newAVError(ret, "Unable to copy codec parameters to bitstream filter", name)
+		}
+	}
+
+	this.avBSFCtx.time_base_in = C.struct_AVRational(timeBase.AVRational())
+
+	for _, option := range options {
+		if err := SetOption(this, option.Key, option.Val); err != nil {
+			this.Free()
+
+			code := -int(syscall.EINVAL)
+			if e, ok := err.(*AVError); ok {
+				code = e.Code
+			}
+
+			return nil, newAVError(code, fmt.Sprintf("Unable to set option '%s' of bitstream filter", option.Key), name)
+		}
+	}
+
+	if ret := int(C.av_bsf_init(this.avBSFCtx)); ret < 0 {
+		this.Free()
+		return nil, newAVError(ret, "Unable to initialize bitstream filter", name)
+	}
+
+	return this, nil
+}
+
+func (this *BitstreamFilter) Free() {
+	if this.avBSFCtx != nil {
+		C.av_bsf_free(&this.avBSFCtx)
+	}
+}
+
+func (this *BitstreamFilter) Name() string {
+	return C.GoString(this.avBSFCtx.filter.name)
+}
+
+// OutputCodecParameters returns codec parameters of the filtered packets.
+// They are owned by the filter, don't free them.
+func (this *BitstreamFilter) OutputCodecParameters() *CodecParameters {
+	return &CodecParameters{avCodecParameters: this.avBSFCtx.par_out}
+}
+
+// InputCodecParameters returns codec parameters the filter was
+// initialized with. They are owned by the filter, don't free them.
+func (this *BitstreamFilter) InputCodecParameters() *CodecParameters {
+	return &CodecParameters{avCodecParameters: this.avBSFCtx.par_in}
+}
+
+func (this *BitstreamFilter) InputTimeBase() AVRational {
+	return AVRational(this.avBSFCtx.time_base_in)
+}
+
+func (this *BitstreamFilter) OutputTimeBase() AVRational {
+	return AVRational(this.avBSFCtx.time_base_out)
+}
+
+// SendPacket submits a packet for filtering, filter takes ownership
+// of the packet data. Nil packet signals the end of the stream.
+func (this *BitstreamFilter) SendPacket(p *Packet) error {
+	var ret int
+
+	if p == nil {
+		ret = int(C.av_bsf_send_packet(this.avBSFCtx, nil))
+	} else {
+		ret = int(C.av_bsf_send_packet(this.avBSFCtx, &p.avPacket))
+	}
+
+	if ret < 0 {
+		return newAVError(ret, "Unable to send packet to bitstream filter", this.Name())
+	}
+
+	return nil
+}
+
+// ReceivePacket returns a filtered packet. ErrAgain is returned if more
+// input is required, ErrEOF if the filter is drained.
+func (this *BitstreamFilter) ReceivePacket() (*Packet, error) {
+	p := NewPacket()
+
+	if ret := int(C.av_bsf_receive_packet(this.avBSFCtx, &p.avPacket)); ret < 0 {
+		return nil, AvError(ret)
+	}
+
+	return p, nil
+}
+
+// Filter sends a packet and returns all the packets filter can output.
+// Nil packet drains the filter.
+func (this *BitstreamFilter) Filter(p *Packet) ([]*Packet, error) {
+	if err := this.SendPacket(p); err != nil {
+		return nil, err
+	}
+
+	result := make([]*Packet, 0)
+
+	for {
+		pkt := NewPacket()
+
+		ret := int(C.av_bsf_receive_packet(this.avBSFCtx, &pkt.avPacket))
+		if ret == AVERROR_EAGAIN || ret == AVERROR_EOF {
+			break
+		}
+		if ret < 0 {
+			for _, r := range result {
+				r.Free()
+			}
+			return nil, newAVError(ret, "Unable to receive packet from bitstream filter", this.Name())
+		}
+
+		result = append(result, pkt)
+	}
+
+	return result, nil
+}
+
+// Flush resets the filter state, e.g. after seeking.
+func (this *BitstreamFilter) Flush() {
+	C.av_bsf_flush(this.avBSFCtx)
+}
+
+// filterPackets passes the packet through the chain of bitstream filters,
+// the packet is returned as is, if the chain is empty. Nil packet drains the chain.
+func filterPackets(bsfs []*BitstreamFilter, p *Packet) ([]*Packet, error) {
+	pkts := make([]*Packet, 0, 1)
+
+	if p != nil {
+		pkts = append(pkts, p)
+	}
+
+	for _, bsf := range bsfs {
+		out := make([]*Packet, 0, len(pkts))
+
+		for i, pkt := range pkts {
+			res, err := bsf.Filter(pkt)
+			if err != nil {
+				freePackets(out)
+				freePackets(pkts[i:])
+				return nil, err
+			}
+
+			out = append(out, res...)
+		}
+
+		if p == nil {
+			res, err := bsf.Filter(nil)
+			if err != nil {
+				freePackets(out)
+				return nil, err
+			}
+
+			out = append(out, res...)
+		}
+
+		pkts = out
+	}
+
+	return pkts, nil
+}
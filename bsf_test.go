@@ -0,0 +1,124 @@
+package gmf
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBitstreamFilter(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	bsf, err := NewBitstreamFilter("null", ist.GetCodecPar(), ist.TimeBase().AVR())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsf.Free()
+
+	if bsf.OutputCodecParameters().GetCodecId() != ist.GetCodecPar().GetCodecId() {
+		t.Fatalf("Expected codec id %d, %d got\n", ist.GetCodecPar().GetCodecId(), bsf.OutputCodecParameters().GetCodecId())
+	}
+
+	cnt := 0
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		pkts, err := bsf.Filter(it.Packet())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cnt += len(pkts)
+		freePackets(pkts)
+	}
+
+	pkts, err := bsf.Filter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt += len(pkts)
+	freePackets(pkts)
+
+	if cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+
+	if _, err := bsf.ReceivePacket(); !isEOF(err) {
+		t.Fatalf("Expected ErrEOF, '%v' got\n", err)
+	}
+}
+
+func TestBitstreamFilterNotFound(t *testing.T) {
+	if _, err := NewBitstreamFilter("not_existing_bsf", nil, AVR{1, 25}); !isAVError(err, AVERROR_BSF_NOT_FOUND) {
+		t.Fatalf("Expected ErrBsfNotFound, '%v' got\n", err)
+	}
+}
+
+func TestBitstreamFilterOptionError(t *testing.T) {
+	_, err := NewBitstreamFilter("null", nil, AVR{1, 25}, &Option{Key: "not_existing_option", Val: 1})
+
+	e, ok := err.(*AVError)
+	if !ok || e.Code != AVERROR_OPTION_NOT_FOUND {
+		t.Fatalf("Expected AVERROR_OPTION_NOT_FOUND, '%v' got\n", err)
+	}
+
+	if e.URL != "null" || !strings.Contains(e.Op, "not_existing_option") {
+		t.Fatalf("Expected error naming the filter and the option, '%v' got\n", err)
+	}
+}
+
+func TestStreamBitstreamFilter(t *testing.T) {
+	f, err := ioutil.TempFile("", "gmf-bsf-*.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	outputCtx, err := NewOutputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ost *Stream
+
+	err = remuxSampleWith(t, outputCtx, func(inputCtx *FmtCtx, ist, s *Stream) {
+		if err := s.AddBitstreamFilter("null"); err != nil {
+			t.Fatal(err)
+		}
+		ost = s
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mpegts muxer sets 1/90000, the chain is re-initialized with it
+	if tb := ost.TimeBase().AVR(); tb != (AVR{1, 90000}) {
+		t.Fatalf("Expected time base 1/90000, %v got\n", tb)
+	}
+
+	if tb := ost.bsfs[0].InputTimeBase().AVR(); tb != ost.TimeBase().AVR() {
+		t.Fatalf("Expected bitstream filter time base %v, %v got\n", ost.TimeBase().AVR(), tb)
+	}
+
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	if cnt := countPackets(t, inputCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
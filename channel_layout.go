@@ -0,0 +1,656 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavcodec libavutil
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "libavcodec/avcodec.h"
+#include "libavutil/bprint.h"
+#include "libavutil/channel_layout.h"
+#include "libavutil/common.h"
+#include "libavutil/frame.h"
+#include "libavutil/mem.h"
+#include "libavutil/opt.h"
+
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+#define GMF_CH_LAYOUT_API 1
+
+typedef AVChannelLayout gmf_ch_layout;
+#else
+// FFmpeg before 5.1 has no AVChannelLayout, only native order masks are supported.
+typedef struct gmf_ch_layout {
+	int      order;
+	int      nb_channels;
+	uint64_t mask;
+} gmf_ch_layout;
+
+#define AV_CHANNEL_ORDER_UNSPEC 0
+#define AV_CHANNEL_ORDER_NATIVE 1
+#endif
+
+static int gmf_ch_layout_from_mask(gmf_ch_layout *l, uint64_t mask) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_from_mask(l, mask);
+#else
+	if (!mask)
+		return AVERROR(EINVAL);
+
+	l->order       = AV_CHANNEL_ORDER_NATIVE;
+	l->nb_channels = av_get_channel_layout_nb_channels(mask);
+	l->mask        = mask;
+
+	return 0;
+#endif
+}
+
+static void gmf_ch_layout_default(gmf_ch_layout *l, int nb_channels) {
+#ifdef GMF_CH_LAYOUT_API
+	av_channel_layout_default(l, nb_channels);
+#else
+	if (gmf_ch_layout_from_mask(l, av_get_default_channel_layout(nb_channels)) < 0) {
+		l->order       = AV_CHANNEL_ORDER_UNSPEC;
+		l->nb_channels = nb_channels;
+		l->mask        = 0;
+	}
+#endif
+}
+
+static int gmf_ch_layout_from_string(gmf_ch_layout *l, const char *str) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_from_string(l, str);
+#else
+	return gmf_ch_layout_from_mask(l, av_get_channel_layout(str));
+#endif
+}
+
+static int gmf_ch_layout_custom(gmf_ch_layout *l, int *channels, int nb_channels) {
+#ifdef GMF_CH_LAYOUT_API
+	int i;
+
+	if (nb_channels <= 0)
+		return AVERROR(EINVAL);
+
+	if (!(l->u.map = av_calloc(nb_channels, sizeof(AVChannelCustom))))
+		return AVERROR(ENOMEM);
+
+	l->order       = AV_CHANNEL_ORDER_CUSTOM;
+	l->nb_channels = nb_channels;
+
+	for (i = 0; i < nb_channels; i++)
+		l->u.map[i].id = channels[i];
+
+	return 0;
+#else
+	return AVERROR(ENOSYS);
+#endif
+}
+
+static int gmf_ch_layout_ambisonic(gmf_ch_layout *l, int order, uint64_t mask) {
+#ifdef GMF_CH_LAYOUT_API
+	if (order < 0)
+		return AVERROR(EINVAL);
+
+	l->order       = AV_CHANNEL_ORDER_AMBISONIC;
+	l->nb_channels = (order + 1) * (order + 1) + av_popcount64(mask);
+	l->u.mask      = mask;
+
+	return av_channel_layout_check(l) ? 0 : AVERROR(EINVAL);
+#else
+	return AVERROR(ENOSYS);
+#endif
+}
+
+static void gmf_ch_layout_uninit(gmf_ch_layout *l) {
+#ifdef GMF_CH_LAYOUT_API
+	av_channel_layout_uninit(l);
+#else
+	memset(l, 0, sizeof(*l));
+#endif
+}
+
+static int gmf_ch_layout_copy(gmf_ch_layout *dst, gmf_ch_layout *src) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(dst, src);
+#else
+	*dst = *src;
+	return 0;
+#endif
+}
+
+static int gmf_ch_layout_order(gmf_ch_layout *l) {
+	return l->order;
+}
+
+static int gmf_ch_layout_nb_channels(gmf_ch_layout *l) {
+	return l->nb_channels;
+}
+
+static uint64_t gmf_ch_layout_mask(gmf_ch_layout *l) {
+	if (l->order != AV_CHANNEL_ORDER_NATIVE)
+		return 0;
+#ifdef GMF_CH_LAYOUT_API
+	return l->u.mask;
+#else
+	return l->mask;
+#endif
+}
+
+static int gmf_ch_layout_channel(gmf_ch_layout *l, int idx) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_channel_from_index(l, idx);
+#else
+	if (l->order != AV_CHANNEL_ORDER_NATIVE || idx < 0 || idx >= l->nb_channels)
+		return -1;
+
+	return av_log2(av_channel_layout_extract_channel(l->mask, idx));
+#endif
+}
+
+static int gmf_ch_layout_index(gmf_ch_layout *l, int channel) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_index_from_channel(l, channel);
+#else
+	if (l->order != AV_CHANNEL_ORDER_NATIVE || channel < 0 || channel >= 64)
+		return AVERROR(EINVAL);
+
+	return av_get_channel_layout_channel_index(l->mask, 1ULL << channel);
+#endif
+}
+
+static int gmf_ch_layout_check(gmf_ch_layout *l) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_check(l);
+#else
+	if (l->nb_channels <= 0)
+		return 0;
+
+	return l->order != AV_CHANNEL_ORDER_NATIVE || av_get_channel_layout_nb_channels(l->mask) == l->nb_channels;
+#endif
+}
+
+static int gmf_ch_layout_compare(gmf_ch_layout *a, gmf_ch_layout *b) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_compare(a, b);
+#else
+	return a->order != b->order || a->nb_channels != b->nb_channels || a->mask != b->mask;
+#endif
+}
+
+static char *gmf_ch_layout_describe(gmf_ch_layout *l) {
+	AVBPrint pbuf;
+	char *result;
+
+	av_bprint_init(&pbuf, 0, AV_BPRINT_SIZE_UNLIMITED);
+#ifdef GMF_CH_LAYOUT_API
+	av_channel_layout_describe_bprint(l, &pbuf);
+#else
+	av_bprint_channel_layout(&pbuf, l->nb_channels, l->mask);
+#endif
+
+	if (av_bprint_finalize(&pbuf, &result) < 0)
+		return NULL;
+
+	return result;
+}
+
+static char *gmf_channel_name(int channel) {
+	char *buf = av_mallocz(64);
+
+	if (!buf)
+		return NULL;
+#ifdef GMF_CH_LAYOUT_API
+	av_channel_name(buf, 64, channel);
+#else
+	if (channel >= 0 && channel < 64 && av_get_channel_name(1ULL << channel))
+		snprintf(buf, 64, "%s", av_get_channel_name(1ULL << channel));
+	else
+		snprintf(buf, 64, "USR%d", channel);
+#endif
+
+	return buf;
+}
+
+static int gmf_channel_from_string(const char *name) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_from_string(name);
+#else
+	uint64_t mask = av_get_channel_layout(name);
+
+	if (av_get_channel_layout_nb_channels(mask) != 1)
+		return -1;
+
+	return av_log2(mask);
+#endif
+}
+
+static int gmf_ch_layout_set_opt(void *obj, const char *key, gmf_ch_layout *l) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_opt_set_chlayout(obj, key, l, AV_OPT_SEARCH_CHILDREN);
+#else
+	if (!strcmp(key, "in_chlayout"))
+		key = "in_channel_layout";
+	else if (!strcmp(key, "out_chlayout"))
+		key = "out_channel_layout";
+	else if (!strcmp(key, "ch_layout"))
+		key = "channel_layout";
+
+	return av_opt_set_channel_layout(obj, key, (int64_t)l->mask, AV_OPT_SEARCH_CHILDREN);
+#endif
+}
+
+static int gmf_ch_layout_from_codec_ctx(gmf_ch_layout *dst, AVCodecContext *ctx) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(dst, &ctx->ch_layout);
+#else
+	if (gmf_ch_layout_from_mask(dst, ctx->channel_layout) < 0 || dst->nb_channels != ctx->channels) {
+		dst->order       = AV_CHANNEL_ORDER_UNSPEC;
+		dst->nb_channels = ctx->channels;
+		dst->mask        = 0;
+	}
+
+	return 0;
+#endif
+}
+
+static int gmf_ch_layout_to_codec_ctx(AVCodecContext *ctx, gmf_ch_layout *src) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(&ctx->ch_layout, src);
+#else
+	ctx->channel_layout = src->mask;
+	ctx->channels       = src->nb_channels;
+
+	return 0;
+#endif
+}
+
+static int gmf_ch_layout_from_frame(gmf_ch_layout *dst, AVFrame *frame) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(dst, &frame->ch_layout);
+#else
+	if (gmf_ch_layout_from_mask(dst, frame->channel_layout) < 0 || dst->nb_channels != frame->channels) {
+		dst->order       = AV_CHANNEL_ORDER_UNSPEC;
+		dst->nb_channels = frame->channels;
+		dst->mask        = 0;
+	}
+
+	return 0;
+#endif
+}
+
+static int gmf_ch_layout_to_frame(AVFrame *frame, gmf_ch_layout *src) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(&frame->ch_layout, src);
+#else
+	frame->channel_layout = src->mask;
+	frame->channels       = src->nb_channels;
+
+	return 0;
+#endif
+}
+
+static int gmf_ch_layout_from_codec_par(gmf_ch_layout *dst, AVCodecParameters *par) {
+#ifdef GMF_CH_LAYOUT_API
+	return av_channel_layout_copy(dst, &par->ch_layout);
+#else
+	if (gmf_ch_layout_from_mask(dst, par->channel_layout) < 0 || dst->nb_channels != par->channels) {
+		dst->order       = AV_CHANNEL_ORDER_UNSPEC;
+		dst->nb_channels = par->channels;
+		dst->mask        = 0;
+	}
+
+	return 0;
+#endif
+}
+
+// gmf_ch_layout_from_codec copies idx-th supported layout of the codec,
+// 1 is returned at the end of the list, or if the codec has no list.
+static int gmf_ch_layout_from_codec(gmf_ch_layout *dst, const AVCodec *codec, int idx) {
+#ifdef GMF_CH_LAYOUT_API
+	if (!codec->ch_layouts || !codec->ch_layouts[idx].nb_channels)
+		return 1;
+
+	return av_channel_layout_copy(dst, &codec->ch_layouts[idx]);
+#else
+	if (!codec->channel_layouts || !codec->channel_layouts[idx])
+		return 1;
+
+	return gmf_ch_layout_from_mask(dst, codec->channel_layouts[idx]);
+#endif
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Channel orders, see enum AVChannelOrder.
+// Custom and ambisonic orders require FFmpeg 5.1 or later.
+const (
+	AV_CHANNEL_ORDER_UNSPEC = iota
+	AV_CHANNEL_ORDER_NATIVE
+	AV_CHANNEL_ORDER_CUSTOM
+	AV_CHANNEL_ORDER_AMBISONIC
+)
+
+// Channel ids, see enum AVChannel.
+// Ids of the native order channels are the bit numbers in channel layout mask.
+const (
+	AV_CHAN_NONE = iota - 1
+	AV_CHAN_FRONT_LEFT
+	AV_CHAN_FRONT_RIGHT
+	AV_CHAN_FRONT_CENTER
+	AV_CHAN_LOW_FREQUENCY
+	AV_CHAN_BACK_LEFT
+	AV_CHAN_BACK_RIGHT
+	AV_CHAN_FRONT_LEFT_OF_CENTER
+	AV_CHAN_FRONT_RIGHT_OF_CENTER
+	AV_CHAN_BACK_CENTER
+	AV_CHAN_SIDE_LEFT
+	AV_CHAN_SIDE_RIGHT
+	AV_CHAN_TOP_CENTER
+	AV_CHAN_TOP_FRONT_LEFT
+	AV_CHAN_TOP_FRONT_CENTER
+	AV_CHAN_TOP_FRONT_RIGHT
+	AV_CHAN_TOP_BACK_LEFT
+	AV_CHAN_TOP_BACK_CENTER
+	AV_CHAN_TOP_BACK_RIGHT
+)
+
+const (
+	AV_CHAN_STEREO_LEFT = iota + 29
+	AV_CHAN_STEREO_RIGHT
+	AV_CHAN_WIDE_LEFT
+	AV_CHAN_WIDE_RIGHT
+	AV_CHAN_SURROUND_DIRECT_LEFT
+	AV_CHAN_SURROUND_DIRECT_RIGHT
+	AV_CHAN_LOW_FREQUENCY_2
+
+	AV_CHAN_UNUSED         = 0x200
+	AV_CHAN_UNKNOWN        = 0x300
+	AV_CHAN_AMBISONIC_BASE = 0x400
+	AV_CHAN_AMBISONIC_END  = 0x7ff
+)
+
+// Named channel layout masks.
+const (
+	AV_CH_LAYOUT_MONO              uint64 = C.AV_CH_LAYOUT_MONO
+	AV_CH_LAYOUT_STEREO            uint64 = C.AV_CH_LAYOUT_STEREO
+	AV_CH_LAYOUT_2POINT1           uint64 = C.AV_CH_LAYOUT_2POINT1
+	AV_CH_LAYOUT_2_1               uint64 = C.AV_CH_LAYOUT_2_1
+	AV_CH_LAYOUT_SURROUND          uint64 = C.AV_CH_LAYOUT_SURROUND
+	AV_CH_LAYOUT_3POINT1           uint64 = C.AV_CH_LAYOUT_3POINT1
+	AV_CH_LAYOUT_4POINT0           uint64 = C.AV_CH_LAYOUT_4POINT0
+	AV_CH_LAYOUT_4POINT1           uint64 = C.AV_CH_LAYOUT_4POINT1
+	AV_CH_LAYOUT_2_2               uint64 = C.AV_CH_LAYOUT_2_2
+	AV_CH_LAYOUT_QUAD              uint64 = C.AV_CH_LAYOUT_QUAD
+	AV_CH_LAYOUT_5POINT0           uint64 = C.AV_CH_LAYOUT_5POINT0
+	AV_CH_LAYOUT_5POINT1           uint64 = C.AV_CH_LAYOUT_5POINT1
+	AV_CH_LAYOUT_5POINT0_BACK      uint64 = C.AV_CH_LAYOUT_5POINT0_BACK
+	AV_CH_LAYOUT_5POINT1_BACK      uint64 = C.AV_CH_LAYOUT_5POINT1_BACK
+	AV_CH_LAYOUT_6POINT0           uint64 = C.AV_CH_LAYOUT_6POINT0
+	AV_CH_LAYOUT_6POINT1           uint64 = C.AV_CH_LAYOUT_6POINT1
+	AV_CH_LAYOUT_7POINT0           uint64 = C.AV_CH_LAYOUT_7POINT0
+	AV_CH_LAYOUT_7POINT1           uint64 = C.AV_CH_LAYOUT_7POINT1
+	AV_CH_LAYOUT_7POINT1_WIDE      uint64 = C.AV_CH_LAYOUT_7POINT1_WIDE
+	AV_CH_LAYOUT_7POINT1_WIDE_BACK uint64 = C.AV_CH_LAYOUT_7POINT1_WIDE_BACK
+	AV_CH_LAYOUT_OCTAGONAL         uint64 = C.AV_CH_LAYOUT_OCTAGONAL
+	AV_CH_LAYOUT_STEREO_DOWNMIX    uint64 = C.AV_CH_LAYOUT_STEREO_DOWNMIX
+)
+
+// ChannelLayout wraps AVChannelLayout. Layouts in native order are described
+// by a channel mask, custom order layouts by the list of channel ids and
+// ambisonic layouts by the ambisonic order.
+//
+// Layouts returned by gmf are copies, Free must be called for them, since
+// custom order layouts hold C allocated channel map.
+type ChannelLayout struct {
+	chLayout C.gmf_ch_layout
+}
+
+// NewChannelLayout creates native order layout from the channel mask,
+// e.g. AV_CH_LAYOUT_STEREO.
+func NewChannelLayout(mask uint64) (*ChannelLayout, error) {
+	l := &ChannelLayout{}
+
+	if ret := int(C.gmf_ch_layout_from_mask(&l.chLayout, C.uint64_t(mask))); ret < 0 {
+		return nil, newAVError(ret, fmt.Sprintf("Unable to create channel layout from mask 0x%x", mask), "")
+	}
+
+	return l, nil
+}
+
+// NewDefaultChannelLayout returns the default layout for the number of channels.
+// If there is no default one, the layout has unspecified order.
+func NewDefaultChannelLayout(nbChannels int) *ChannelLayout {
+	l := &ChannelLayout{}
+
+	C.gmf_ch_layout_default(&l.chLayout, C.int(nbChannels))
+
+	return l
+}
+
+// ParseChannelLayout parses layout name ("stereo", "5.1(side)"), channel list
+// ("FL+FR+LFE"), channel count ("3c", "3 channels"), mask ("0x3")
+// or ambisonic layout ("ambisonic 1"). Format is the same as for ffmpeg -ch_layout.
+func ParseChannelLayout(s string) (*ChannelLayout, error) {
+	l := &ChannelLayout{}
+
+	cs := C.CString(s)
+	defer C.free(unsafe.Pointer(cs))
+
+	if ret := int(C.gmf_ch_layout_from_string(&l.chLayout, cs)); ret < 0 {
+		return nil, newAVError(ret, fmt.Sprintf("Unable to parse channel layout '%s'", s), "")
+	}
+
+	return l, nil
+}
+
+// NewCustomChannelLayout creates custom order layout from the list of AV_CHAN_* ids,
+// e.g. the same channel may appear twice or channels order differs from native.
+func NewCustomChannelLayout(channels []int) (*ChannelLayout, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("channels list is empty")
+	}
+
+	l := &ChannelLayout{}
+
+	cchannels := make([]C.int, len(channels))
+	for i, ch := range channels {
+		cchannels[i] = C.int(ch)
+	}
+
+	if ret := int(C.gmf_ch_layout_custom(&l.chLayout, &cchannels[0], C.int(len(channels)))); ret < 0 {
+		return nil, newAVError(ret, "Unable to create custom channel layout", "")
+	}
+
+	return l, nil
+}
+
+// NewAmbisonicChannelLayout creates ambisonic layout of the given order,
+// mask is a native order mask of the additional non-diegetic channels, may be 0.
+func NewAmbisonicChannelLayout(order int, mask uint64) (*ChannelLayout, error) {
+	l := &ChannelLayout{}
+
+	if ret := int(C.gmf_ch_layout_ambisonic(&l.chLayout, C.int(order), C.uint64_t(mask))); ret < 0 {
+		return nil, newAVError(ret, fmt.Sprintf("Unable to create ambisonic channel layout of order %d", order), "")
+	}
+
+	return l, nil
+}
+
+func (l *ChannelLayout) Free() {
+	C.gmf_ch_layout_uninit(&l.chLayout)
+}
+
+// Copy returns deep copy of the layout.
+func (l *ChannelLayout) Copy() (*ChannelLayout, error) {
+	dst := &ChannelLayout{}
+
+	if ret := int(C.gmf_ch_layout_copy(&dst.chLayout, &l.chLayout)); ret < 0 {
+		return nil, AvError(ret)
+	}
+
+	return dst, nil
+}
+
+// Order returns one of AV_CHANNEL_ORDER_*.
+func (l *ChannelLayout) Order() int {
+	return int(C.gmf_ch_layout_order(&l.chLayout))
+}
+
+func (l *ChannelLayout) NbChannels() int {
+	return int(C.gmf_ch_layout_nb_channels(&l.chLayout))
+}
+
+// Mask returns the channel mask of the native order layout, 0 for other orders.
+func (l *ChannelLayout) Mask() uint64 {
+	return uint64(C.gmf_ch_layout_mask(&l.chLayout))
+}
+
+// Channel returns AV_CHAN_* id of the channel at idx, AV_CHAN_NONE if idx is out of range.
+func (l *ChannelLayout) Channel(idx int) int {
+	return int(C.gmf_ch_layout_channel(&l.chLayout, C.int(idx)))
+}
+
+// Index returns index of the AV_CHAN_* channel in the layout, -1 if it is not present.
+func (l *ChannelLayout) Index(channel int) int {
+	if idx := int(C.gmf_ch_layout_index(&l.chLayout, C.int(channel))); idx >= 0 {
+		return idx
+	}
+
+	return -1
+}
+
+// IsValid checks, that the layout is consistent and has at least one channel.
+func (l *ChannelLayout) IsValid() bool {
+	return int(C.gmf_ch_layout_check(&l.chLayout)) == 1
+}
+
+func (l *ChannelLayout) Equal(other *ChannelLayout) bool {
+	if other == nil {
+		return false
+	}
+
+	return int(C.gmf_ch_layout_compare(&l.chLayout, &other.chLayout)) == 0
+}
+
+// String returns human readable description, which is accepted by ParseChannelLayout.
+func (l *ChannelLayout) String() string {
+	cstr := C.gmf_ch_layout_describe(&l.chLayout)
+	if cstr == nil {
+		return ""
+	}
+	defer C.av_free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// filterArg formats layout for 'channel_layout' argument of the abuffer filter.
+func (l *ChannelLayout) filterArg() string {
+	switch l.Order() {
+	case AV_CHANNEL_ORDER_UNSPEC:
+		return ""
+
+	case AV_CHANNEL_ORDER_NATIVE:
+		return fmt.Sprintf("0x%x", l.Mask())
+	}
+
+	return l.String()
+}
+
+// setOption sets AV_OPT_TYPE_CHLAYOUT option of the obj. Before FFmpeg 5.1
+// AV_OPT_TYPE_CHANNEL_LAYOUT option is set, "in_chlayout", "out_chlayout"
+// and "ch_layout" keys are mapped to the old names.
+func (l *ChannelLayout) setOption(obj unsafe.Pointer, key *C.char) int {
+	return int(C.gmf_ch_layout_set_opt(obj, key, &l.chLayout))
+}
+
+// ChannelName returns abbreviated name of the AV_CHAN_* channel, e.g. "FL".
+func ChannelName(channel int) string {
+	cstr := C.gmf_channel_name(C.int(channel))
+	if cstr == nil {
+		return ""
+	}
+	defer C.av_free(unsafe.Pointer(cstr))
+
+	return C.GoString(cstr)
+}
+
+// ChannelFromName returns AV_CHAN_* id by abbreviated channel name, AV_CHAN_NONE if it is unknown.
+func ChannelFromName(name string) int {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	return int(C.gmf_channel_from_string(cname))
+}
+
+func channelLayoutFromCodecCtx(ctx *C.struct_AVCodecContext) *ChannelLayout {
+	l := &ChannelLayout{}
+
+	if int(C.gmf_ch_layout_from_codec_ctx(&l.chLayout, ctx)) < 0 {
+		return nil
+	}
+
+	return l
+}
+
+func (l *ChannelLayout) toCodecCtx(ctx *C.struct_AVCodecContext) error {
+	if ret := int(C.gmf_ch_layout_to_codec_ctx(ctx, &l.chLayout)); ret < 0 {
+		return AvError(ret)
+	}
+
+	return nil
+}
+
+func channelLayoutFromFrame(frame *C.struct_AVFrame) *ChannelLayout {
+	l := &ChannelLayout{}
+
+	if int(C.gmf_ch_layout_from_frame(&l.chLayout, frame)) < 0 {
+		return nil
+	}
+
+	return l
+}
+
+func (l *ChannelLayout) toFrame(frame *C.struct_AVFrame) error {
+	if ret := int(C.gmf_ch_layout_to_frame(frame, &l.chLayout)); ret < 0 {
+		return AvError(ret)
+	}
+
+	return nil
+}
+
+func channelLayoutFromCodecPar(par *C.struct_AVCodecParameters) *ChannelLayout {
+	l := &ChannelLayout{}
+
+	if int(C.gmf_ch_layout_from_codec_par(&l.chLayout, par)) < 0 {
+		return nil
+	}
+
+	return l
+}
+
+// codecChannelLayouts returns layouts supported by the codec, nil if any layout is supported.
+func codecChannelLayouts(codec *C.struct_AVCodec) []*ChannelLayout {
+	var result []*ChannelLayout
+
+	for i := 0; ; i++ {
+		l := &ChannelLayout{}
+
+		if ret := int(C.gmf_ch_layout_from_codec(&l.chLayout, codec, C.int(i))); ret != 0 {
+			break
+		}
+
+		result = append(result, l)
+	}
+
+	return result
+}
@@ -0,0 +1,136 @@
+package gmf
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestChannelLayout(t *testing.T) {
+	l, err := ParseChannelLayout("stereo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Free()
+
+	if l.Order() != AV_CHANNEL_ORDER_NATIVE || l.NbChannels() != 2 || l.Mask() != AV_CH_LAYOUT_STEREO {
+		t.Fatalf("Expected native stereo layout, order %d, %d channels, mask 0x%x got\n", l.Order(), l.NbChannels(), l.Mask())
+	}
+
+	if l.String() != "stereo" {
+		t.Fatalf("Expected 'stereo', '%s' got\n", l.String())
+	}
+
+	if l.Channel(1) != AV_CHAN_FRONT_RIGHT || l.Index(AV_CHAN_FRONT_RIGHT) != 1 || l.Index(AV_CHAN_LOW_FREQUENCY) != -1 {
+		t.Fatalf("Unexpected channel order in %s\n", l)
+	}
+
+	if ChannelName(AV_CHAN_FRONT_LEFT) != "FL" || ChannelFromName("FR") != AV_CHAN_FRONT_RIGHT {
+		t.Fatalf("Unexpected channel names\n")
+	}
+
+	def := NewDefaultChannelLayout(6)
+	defer def.Free()
+
+	if def.Mask() != AV_CH_LAYOUT_5POINT1 || !def.IsValid() {
+		t.Fatalf("Expected 5.1 default layout, %s got\n", def)
+	}
+
+	if _, err := ParseChannelLayout("not a layout"); err == nil {
+		t.Fatalf("Expected error parsing invalid layout\n")
+	}
+}
+
+func TestChannelLayoutCustom(t *testing.T) {
+	l, err := NewCustomChannelLayout([]int{AV_CHAN_FRONT_RIGHT, AV_CHAN_FRONT_LEFT})
+	if isAVError(err, -int(syscall.ENOSYS)) {
+		t.Skip("custom channel layouts are not supported by this FFmpeg version")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Free()
+
+	if l.Order() != AV_CHANNEL_ORDER_CUSTOM || l.Channel(0) != AV_CHAN_FRONT_RIGHT || l.Mask() != 0 {
+		t.Fatalf("Unexpected custom layout %s\n", l)
+	}
+
+	c, err := l.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Free()
+
+	if !c.Equal(l) {
+		t.Fatalf("Expected copy %s equal to %s\n", c, l)
+	}
+
+	a, err := NewAmbisonicChannelLayout(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Free()
+
+	if a.Order() != AV_CHANNEL_ORDER_AMBISONIC || a.NbChannels() != 4 {
+		t.Fatalf("Expected first order ambisonic layout, %s got\n", a)
+	}
+}
+
+func TestFrameChannelLayout(t *testing.T) {
+	l, err := NewChannelLayout(AV_CH_LAYOUT_5POINT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Free()
+
+	frame, err := NewAudioFrameWithLayout(AV_SAMPLE_FMT_S16, l, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer frame.Free()
+
+	fl := frame.ChLayout()
+	defer fl.Free()
+
+	if !fl.Equal(l) || frame.Channels() != 6 || frame.ChannelLayout() != int(AV_CH_LAYOUT_5POINT1) {
+		t.Fatalf("Expected frame layout %s, %s got\n", l, fl)
+	}
+}
+
+func TestSwrChannelLayout(t *testing.T) {
+	stereo := NewDefaultChannelLayout(2)
+	defer stereo.Free()
+
+	mono := NewDefaultChannelLayout(1)
+	defer mono.Free()
+
+	options := []*Option{
+		{"in_chlayout", stereo},
+		{"in_sample_rate", 44100},
+		{"in_sample_fmt", AV_SAMPLE_FMT_S16},
+		{"out_chlayout", mono},
+		{"out_sample_rate", 44100},
+		{"out_sample_fmt", AV_SAMPLE_FMT_S16},
+	}
+
+	swrCtx, err := NewSwrCtxWithLayout(options, mono, AV_SAMPLE_FMT_S16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer swrCtx.Free()
+
+	input, err := NewAudioFrameWithLayout(AV_SAMPLE_FMT_S16, stereo, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Free()
+
+	output, err := swrCtx.Convert(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer output.Free()
+
+	if output.Channels() != 1 || output.ChannelLayout() != int(AV_CH_LAYOUT_MONO) {
+		t.Fatalf("Expected mono output frame, %d channels got\n", output.Channels())
+	}
+}
@@ -0,0 +1,124 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavutil
+
+#include "libavformat/avformat.h"
+#include "libavutil/mem.h"
+
+static AVChapter *gmf_chapter(AVFormatContext *ctx, int idx) {
+	return ctx->chapters[idx];
+}
+
+// chapter is freed by avformat_free_context
+static AVChapter *gmf_new_chapter(AVFormatContext *ctx, int64_t id, AVRational tb, int64_t start, int64_t end) {
+	AVChapter *ch = av_mallocz(sizeof(AVChapter));
+	if (!ch) {
+		return NULL;
+	}
+
+	ch->id        = id;
+	ch->time_base = tb;
+	ch->start     = start;
+	ch->end       = end;
+
+	if (av_dynarray_add_nofree(&ctx->chapters, (int *)&ctx->nb_chapters, ch) < 0) {
+		av_free(ch);
+		return NULL;
+	}
+
+	return ch;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Chapter is a copy of the AVChapter. Start and End are in TimeBase units.
+type Chapter struct {
+	Id       int64
+	TimeBase AVR
+	Start    int64
+	End      int64
+	Metadata map[string]string
+}
+
+// StartTime returns chapter start in seconds.
+func (c *Chapter) StartTime() float64 {
+	return float64(c.Start) * c.TimeBase.Av2qd()
+}
+
+// EndTime returns chapter end in seconds.
+func (c *Chapter) EndTime() float64 {
+	return float64(c.End) * c.TimeBase.Av2qd()
+}
+
+// Title returns "title" metadata of the chapter.
+func (c *Chapter) Title() string {
+	return c.Metadata["title"]
+}
+
+// Chapters returns copies of the context chapters.
+func (this *FmtCtx) Chapters() []*Chapter {
+	result := make([]*Chapter, 0, int(this.avCtx.nb_chapters))
+
+	for i := 0; i < int(this.avCtx.nb_chapters); i++ {
+		ch := C.gmf_chapter(this.avCtx, C.int(i))
+
+		result = append(result, &Chapter{
+			Id:       int64(ch.id),
+			TimeBase: AVRational(ch.time_base).AVR(),
+			Start:    int64(ch.start),
+			End:      int64(ch.end),
+			Metadata: (&Dict{dict: ch.metadata}).Map(),
+		})
+	}
+
+	return result
+}
+
+// AddChapter adds chapter to the output context, it must be called before WriteHeader.
+// Chapter ids must be unique.
+func (this *FmtCtx) AddChapter(c *Chapter) error {
+	if c.TimeBase.Num <= 0 || c.TimeBase.Den <= 0 {
+		return errors.New(fmt.Sprintf("Invalid chapter %d time base %s", c.Id, c.TimeBase))
+	}
+
+	if c.End < c.Start {
+		return errors.New(fmt.Sprintf("Invalid chapter %d, end %d is before start %d", c.Id, c.End, c.Start))
+	}
+
+	for _, existing := range this.Chapters() {
+		if existing.Id == c.Id {
+			return errors.New(fmt.Sprintf("Chapter %d already exists", c.Id))
+		}
+	}
+
+	ch := C.gmf_new_chapter(this.avCtx, C.int64_t(c.Id), C.struct_AVRational(c.TimeBase.AVRational()), C.int64_t(c.Start), C.int64_t(c.End))
+	if ch == nil {
+		return errors.New("unable to allocate chapter")
+	}
+
+	return replaceDict(&ch.metadata, c.Metadata)
+}
+
+// CopyChapters adds chapters of the src context, e.g. input one during remux,
+// start and end are rescaled to timeBase.
+func (this *FmtCtx) CopyChapters(src *FmtCtx, timeBase AVR) error {
+	for _, c := range src.Chapters() {
+		c.Start = RescaleQ(c.Start, c.TimeBase.AVRational(), timeBase.AVRational())
+		c.End = RescaleQ(c.End, c.TimeBase.AVRational(), timeBase.AVRational())
+		c.TimeBase = timeBase
+
+		if err := this.AddChapter(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package gmf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChapters(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chapters := []*Chapter{
+		{Id: 1, TimeBase: AVR{1, 1000}, Start: 0, End: 500, Metadata: map[string]string{"title": "Intro"}},
+		{Id: 2, TimeBase: AVR{1, 1000}, Start: 500, End: 1000, Metadata: map[string]string{"title": "Outro"}},
+	}
+
+	for _, c := range chapters {
+		if err := outputCtx.AddChapter(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := outputCtx.AddChapter(chapters[0]); err == nil {
+		t.Fatalf("Expected error adding duplicated chapter\n")
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	result := inputCtx.Chapters()
+	if len(result) != len(chapters) {
+		t.Fatalf("Expected %d chapters, %d got\n", len(chapters), len(result))
+	}
+
+	ms := AVR{1, 1000}.AVRational()
+
+	for i, c := range result {
+		start := RescaleQ(c.Start, c.TimeBase.AVRational(), ms)
+		end := RescaleQ(c.End, c.TimeBase.AVRational(), ms)
+
+		if c.Title() != chapters[i].Title() || start != chapters[i].Start || end != chapters[i].End {
+			t.Fatalf("Expected chapter '%s' %d-%d ms, '%s' %d-%d got\n",
+				chapters[i].Title(), chapters[i].Start, chapters[i].End, c.Title(), start, end)
+		}
+	}
+
+	copyCtx, err := NewOutputCtxToWriter(new(bytes.Buffer), "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copyCtx.Free()
+
+	if err := copyCtx.CopyChapters(inputCtx, AVR{1, 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	copied := copyCtx.Chapters()
+	if len(copied) != 2 || copied[1].TimeBase != (AVR{1, 10}) || copied[1].Start != 5 || copied[1].End != 10 {
+		t.Fatalf("Expected rescaled chapters, %v got\n", copied)
+	}
+}
@@ -7,6 +7,35 @@ package gmf
 #include <stdlib.h>
 #include "libavcodec/avcodec.h"
 #include "libavutil/pixfmt.h"
+#include "libavutil/samplefmt.h"
+
+#ifdef AV_PROFILE_UNKNOWN
+#define GMF_PROFILE_UNKNOWN AV_PROFILE_UNKNOWN
+#else
+#define GMF_PROFILE_UNKNOWN FF_PROFILE_UNKNOWN
+#endif
+
+// lists of the codec are terminated by -1 (pixel and sample formats) or 0 (sample rates)
+
+static int gmf_codec_pix_fmt(const AVCodec *codec, int idx) {
+	return codec->pix_fmts ? codec->pix_fmts[idx] : AV_PIX_FMT_NONE;
+}
+
+static int gmf_codec_sample_fmt(const AVCodec *codec, int idx) {
+	return codec->sample_fmts ? codec->sample_fmts[idx] : AV_SAMPLE_FMT_NONE;
+}
+
+static int gmf_codec_sample_rate(const AVCodec *codec, int idx) {
+	return codec->supported_samplerates ? codec->supported_samplerates[idx] : 0;
+}
+
+static const AVProfile *gmf_codec_profile(const AVCodec *codec, int idx) {
+	if (!codec->profiles || codec->profiles[idx].profile == GMF_PROFILE_UNKNOWN) {
+		return NULL;
+	}
+
+	return &codec->profiles[idx];
+}
 
 */
 import "C"
@@ -18,8 +47,9 @@ import (
 )
 
 var (
-	AVMEDIA_TYPE_AUDIO int32 = C.AVMEDIA_TYPE_AUDIO
-	AVMEDIA_TYPE_VIDEO int32 = C.AVMEDIA_TYPE_VIDEO
+	AVMEDIA_TYPE_AUDIO    int32 = C.AVMEDIA_TYPE_AUDIO
+	AVMEDIA_TYPE_VIDEO    int32 = C.AVMEDIA_TYPE_VIDEO
+	AVMEDIA_TYPE_SUBTITLE int32 = C.AVMEDIA_TYPE_SUBTITLE
 
 	AV_PIX_FMT_BGR24    int32 = C.AV_PIX_FMT_BGR24
 	AV_PIX_FMT_BGR32    int32 = C.AV_PIX_FMT_BGR32
@@ -93,7 +123,7 @@ var (
 	FF_PROFILE_MPEG4_SIMPLE_STUDIO             int = C.FF_PROFILE_MPEG4_SIMPLE_STUDIO
 	FF_PROFILE_MPEG4_ADVANCED_SIMPLE           int = C.FF_PROFILE_MPEG4_ADVANCED_SIMPLE
 
-	AV_NOPTS_VALUE int64 = C.INT64_MAX
+	AV_NOPTS_VALUE int64 = C.INT64_MIN
 
 	FF_PROFILE_JPEG2000_CSTREAM_RESTRICTION_0  int = C.FF_PROFILE_JPEG2000_CSTREAM_RESTRICTION_0
 	FF_PROFILE_JPEG2000_CSTREAM_RESTRICTION_1  int = C.FF_PROFILE_JPEG2000_CSTREAM_RESTRICTION_1
@@ -112,6 +142,27 @@ var (
 	FF_PROFILE_HEVC_REXT               int = C.FF_PROFILE_HEVC_REXT
 )
 
+// Codec capabilities, see AV_CODEC_CAP_* of avcodec.h.
+const (
+	AV_CODEC_CAP_DR1                 int = C.AV_CODEC_CAP_DR1
+	AV_CODEC_CAP_DELAY               int = C.AV_CODEC_CAP_DELAY
+	AV_CODEC_CAP_SMALL_LAST_FRAME    int = C.AV_CODEC_CAP_SMALL_LAST_FRAME
+	AV_CODEC_CAP_EXPERIMENTAL        int = C.AV_CODEC_CAP_EXPERIMENTAL
+	AV_CODEC_CAP_CHANNEL_CONF        int = C.AV_CODEC_CAP_CHANNEL_CONF
+	AV_CODEC_CAP_FRAME_THREADS       int = C.AV_CODEC_CAP_FRAME_THREADS
+	AV_CODEC_CAP_SLICE_THREADS       int = C.AV_CODEC_CAP_SLICE_THREADS
+	AV_CODEC_CAP_VARIABLE_FRAME_SIZE int = C.AV_CODEC_CAP_VARIABLE_FRAME_SIZE
+	AV_CODEC_CAP_AVOID_PROBING       int = C.AV_CODEC_CAP_AVOID_PROBING
+	AV_CODEC_CAP_HARDWARE            int = C.AV_CODEC_CAP_HARDWARE
+	AV_CODEC_CAP_HYBRID              int = C.AV_CODEC_CAP_HYBRID
+)
+
+// Profile is a profile supported by the codec, e.g. {FF_PROFILE_H264_HIGH, "High"}.
+type Profile struct {
+	Id   int
+	Name string
+}
+
 type Codec struct {
 	avCodec *C.struct_AVCodec
 	CgoMemoryManage
@@ -138,7 +189,7 @@ func FindDecoder(i interface{}) (*Codec, error) {
 	}
 
 	if avc == nil {
-		return nil, errors.New(fmt.Sprintf("Unable to find codec by value '%v'", i))
+		return nil, newAVError(AVERROR_DECODER_NOT_FOUND, fmt.Sprintf("Unable to find codec by value '%v'", i), "")
 	}
 
 	return &Codec{avCodec: avc, decoder: true}, nil
@@ -164,7 +215,7 @@ func FindEncoder(i interface{}) (*Codec, error) {
 	}
 
 	if avc == nil {
-		return nil, errors.New(fmt.Sprintf("Unable to find codec by value '%v'", i))
+		return nil, newAVError(AVERROR_ENCODER_NOT_FOUND, fmt.Sprintf("Unable to find codec by value '%v'", i), "")
 	}
 
 	return &Codec{avCodec: avc}, nil
@@ -197,3 +248,171 @@ func (this *Codec) IsExperimental() bool {
 func (this *Codec) IsDecoder() bool {
 	return this.decoder
 }
+
+func (this *Codec) IsEncoder() bool {
+	return C.av_codec_is_encoder(this.avCodec) != 0
+}
+
+// WrapperName returns name of the external library, e.g. "libx264", or
+// of the hardware API, e.g. "cuvid", the codec wraps. Empty for native codecs.
+func (this *Codec) WrapperName() string {
+	if this.avCodec.wrapper_name == nil {
+		return ""
+	}
+
+	return C.GoString(this.avCodec.wrapper_name)
+}
+
+// Capabilities returns AV_CODEC_CAP_* flags of the codec.
+func (this *Codec) Capabilities() int {
+	return int(this.avCodec.capabilities)
+}
+
+func (this *Codec) HasCapability(capability int) bool {
+	return this.Capabilities()&capability != 0
+}
+
+// PixFmts returns pixel formats supported by the video codec, nil if unknown.
+func (this *Codec) PixFmts() []int32 {
+	var result []int32
+
+	for i := 0; ; i++ {
+		pixFmt := int32(C.gmf_codec_pix_fmt(this.avCodec, C.int(i)))
+		if pixFmt == AV_PIX_FMT_NONE {
+			break
+		}
+
+		result = append(result, pixFmt)
+	}
+
+	return result
+}
+
+// SampleFmts returns sample formats supported by the audio codec, nil if unknown.
+func (this *Codec) SampleFmts() []int32 {
+	var result []int32
+
+	for i := 0; ; i++ {
+		sampleFmt := int32(C.gmf_codec_sample_fmt(this.avCodec, C.int(i)))
+		if sampleFmt == AV_SAMPLE_FMT_NONE {
+			break
+		}
+
+		result = append(result, sampleFmt)
+	}
+
+	return result
+}
+
+// SampleRates returns sample rates supported by the audio codec, nil if any rate is supported.
+func (this *Codec) SampleRates() []int {
+	var result []int
+
+	for i := 0; ; i++ {
+		rate := int(C.gmf_codec_sample_rate(this.avCodec, C.int(i)))
+		if rate == 0 {
+			break
+		}
+
+		result = append(result, rate)
+	}
+
+	return result
+}
+
+// ChannelLayouts returns layouts supported by the audio codec, nil if any layout
+// is supported. Layouts should be freed by caller.
+func (this *Codec) ChannelLayouts() []*ChannelLayout {
+	return codecChannelLayouts(this.avCodec)
+}
+
+// Profiles returns profiles the codec recognizes, nil if unknown.
+func (this *Codec) Profiles() []Profile {
+	var result []Profile
+
+	for i := 0; ; i++ {
+		p := C.gmf_codec_profile(this.avCodec, C.int(i))
+		if p == nil {
+			break
+		}
+
+		result = append(result, Profile{Id: int(p.profile), Name: C.GoString(p.name)})
+	}
+
+	return result
+}
+
+// SupportsPixFmt reports whether the codec accepts the pixel format.
+// It's true, if supported formats are unknown.
+func (this *Codec) SupportsPixFmt(pixFmt int32) bool {
+	pixFmts := this.PixFmts()
+
+	for _, f := range pixFmts {
+		if f == pixFmt {
+			return true
+		}
+	}
+
+	return len(pixFmts) == 0
+}
+
+// SupportsSampleFmt reports whether the codec accepts the sample format.
+// It's true, if supported formats are unknown.
+func (this *Codec) SupportsSampleFmt(sampleFmt int32) bool {
+	sampleFmts := this.SampleFmts()
+
+	for _, f := range sampleFmts {
+		if f == sampleFmt {
+			return true
+		}
+	}
+
+	return len(sampleFmts) == 0
+}
+
+// SupportsSampleRate reports whether the codec accepts the sample rate.
+func (this *Codec) SupportsSampleRate(rate int) bool {
+	rates := this.SampleRates()
+
+	for _, r := range rates {
+		if r == rate {
+			return true
+		}
+	}
+
+	return len(rates) == 0
+}
+
+// CodecIterator iterates over all the registered codecs, e.g.:
+//
+//	it := NewCodecIterator()
+//	for it.Next() {
+//		if codec := it.Codec(); codec.IsEncoder() && codec.Type() == int(AVMEDIA_TYPE_VIDEO) {
+//			...
+//		}
+//	}
+type CodecIterator struct {
+	opaque uintptr
+	codec  *Codec
+}
+
+func NewCodecIterator() *CodecIterator {
+	return &CodecIterator{}
+}
+
+// Next advances to the next codec, false is returned after the last one.
+func (it *CodecIterator) Next() bool {
+	avc := C.av_codec_iterate((*unsafe.Pointer)(unsafe.Pointer(&it.opaque)))
+	if avc == nil {
+		it.codec = nil
+		return false
+	}
+
+	it.codec = &Codec{avCodec: avc, decoder: C.av_codec_is_decoder(avc) != 0}
+
+	return true
+}
+
+func (it *CodecIterator) Codec() *Codec {
+	return it.codec
+}
@@ -18,6 +18,10 @@ package gmf
 
 #define HAVE_THREADS 1
 
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+#define GMF_CH_LAYOUT_API 1
+#endif
+
 static int32_t gmf_select_sample_fmt(AVCodec *codec)
 {
     if (codec && codec->sample_fmts) {
@@ -69,11 +73,25 @@ static int gmf_check_sample_rate(AVCodec *codec, int input_sample_rate) {
     return 0;
 }
 
-static int select_channel_layout(AVCodec *codec) {
-    const uint64_t *p;
+static uint64_t select_channel_layout(AVCodec *codec) {
     uint64_t best_ch_layout = 0;
     int best_nb_channels    = 0;
 
+#ifdef GMF_CH_LAYOUT_API
+    const AVChannelLayout *p;
+
+    if (!codec->ch_layouts)
+        return AV_CH_LAYOUT_STEREO;
+
+    for (p = codec->ch_layouts; p->nb_channels; p++) {
+        if (p->order == AV_CHANNEL_ORDER_NATIVE && p->nb_channels > best_nb_channels) {
+            best_ch_layout   = p->u.mask;
+            best_nb_channels = p->nb_channels;
+        }
+    }
+#else
+    const uint64_t *p;
+
     if (!codec->channel_layouts)
         return AV_CH_LAYOUT_STEREO;
 
@@ -87,24 +105,91 @@ static int select_channel_layout(AVCodec *codec) {
         }
         p++;
     }
+#endif
     return best_ch_layout;
 }
 
+static int gmf_codec_ctx_get_channels(AVCodecContext *ctx) {
+#ifdef GMF_CH_LAYOUT_API
+    return ctx->ch_layout.nb_channels;
+#else
+    return ctx->channels;
+#endif
+}
+
+static void gmf_codec_ctx_set_channels(AVCodecContext *ctx, int channels) {
+#ifdef GMF_CH_LAYOUT_API
+    if (ctx->ch_layout.nb_channels != channels) {
+        av_channel_layout_uninit(&ctx->ch_layout);
+        ctx->ch_layout.order       = AV_CHANNEL_ORDER_UNSPEC;
+        ctx->ch_layout.nb_channels = channels;
+    }
+#else
+    ctx->channels = channels;
+#endif
+}
+
+static uint64_t gmf_codec_ctx_get_channel_layout(AVCodecContext *ctx) {
+#ifdef GMF_CH_LAYOUT_API
+    return ctx->ch_layout.order == AV_CHANNEL_ORDER_NATIVE ? ctx->ch_layout.u.mask : 0;
+#else
+    return ctx->channel_layout;
+#endif
+}
+
+static void gmf_codec_ctx_set_channel_layout(AVCodecContext *ctx, uint64_t layout) {
+#ifdef GMF_CH_LAYOUT_API
+    int channels = ctx->ch_layout.nb_channels;
+
+    av_channel_layout_uninit(&ctx->ch_layout);
+
+    if (!layout || av_channel_layout_from_mask(&ctx->ch_layout, layout) < 0) {
+        ctx->ch_layout.order       = AV_CHANNEL_ORDER_UNSPEC;
+        ctx->ch_layout.nb_channels = channels;
+    }
+#else
+    ctx->channel_layout = layout;
+#endif
+}
+
+static uint64_t gmf_default_channel_layout(int channels) {
+#ifdef GMF_CH_LAYOUT_API
+    AVChannelLayout ch_layout;
+    uint64_t result = 0;
+
+    av_channel_layout_default(&ch_layout, channels);
+    if (ch_layout.order == AV_CHANNEL_ORDER_NATIVE)
+        result = ch_layout.u.mask;
+    av_channel_layout_uninit(&ch_layout);
+
+    return result;
+#else
+    return av_get_default_channel_layout(channels);
+#endif
+}
+
 static void call_av_freep(AVCodecContext *out){
     return av_freep(&out);
 }
 
-static char * gmf_get_channel_layout_name(int channels, int layout) {
+static char * gmf_get_channel_layout_name(int channels, uint64_t layout) {
     AVBPrint pbuf;
+    char *result;
 
-    av_bprint_init(&pbuf, 0, 1);
-    av_bprint_channel_layout(&pbuf, channels, layout);
+    av_bprint_init(&pbuf, 0, AV_BPRINT_SIZE_UNLIMITED);
+#ifdef GMF_CH_LAYOUT_API
+    AVChannelLayout ch_layout = { 0 };
 
-    char *result = av_mallocz(pbuf.len);
-
-    memcpy(result, pbuf.str, pbuf.len);
+    if (!layout || av_channel_layout_from_mask(&ch_layout, layout) < 0)
+        av_channel_layout_default(&ch_layout, channels);
+    av_channel_layout_describe_bprint(&ch_layout, &pbuf);
+    av_channel_layout_uninit(&ch_layout);
+#else
+    av_bprint_channel_layout(&pbuf, channels, layout);
+#endif
 
-    av_bprint_clear(&pbuf);
+    if (av_bprint_finalize(&pbuf, &result) < 0)
+        return NULL;
 
     return result;
 }
@@ -116,7 +201,6 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"syscall"
 	"unsafe"
 )
 
@@ -146,6 +230,15 @@ var (
 	AV_CODEC_ID_GIF        int = C.AV_CODEC_ID_GIF
 	AV_CODEC_ID_RAWVIDEO   int = C.AV_CODEC_ID_RAWVIDEO
 
+	AV_CODEC_ID_DVD_SUBTITLE      int = C.AV_CODEC_ID_DVD_SUBTITLE
+	AV_CODEC_ID_DVB_SUBTITLE      int = C.AV_CODEC_ID_DVB_SUBTITLE
+	AV_CODEC_ID_TEXT              int = C.AV_CODEC_ID_TEXT
+	AV_CODEC_ID_MOV_TEXT          int = C.AV_CODEC_ID_MOV_TEXT
+	AV_CODEC_ID_HDMV_PGS_SUBTITLE int = C.AV_CODEC_ID_HDMV_PGS_SUBTITLE
+	AV_CODEC_ID_SUBRIP            int = C.AV_CODEC_ID_SUBRIP
+	AV_CODEC_ID_WEBVTT            int = C.AV_CODEC_ID_WEBVTT
+	AV_CODEC_ID_ASS               int = C.AV_CODEC_ID_ASS
+
 	CODEC_FLAG_GLOBAL_HEADER int   = C.AV_CODEC_FLAG_GLOBAL_HEADER
 	AV_CODEC_FLAG_QSCALE     int32 = C.AV_CODEC_FLAG_QSCALE
 
@@ -155,11 +248,12 @@ var (
 
 	FF_QP2LAMBDA int = C.FF_QP2LAMBDA
 
-	AV_SAMPLE_FMT_U8  int32 = C.AV_SAMPLE_FMT_U8
-	AV_SAMPLE_FMT_S16 int32 = C.AV_SAMPLE_FMT_S16
-	AV_SAMPLE_FMT_S32 int32 = C.AV_SAMPLE_FMT_S32
-	AV_SAMPLE_FMT_FLT int32 = C.AV_SAMPLE_FMT_FLT
-	AV_SAMPLE_FMT_DBL int32 = C.AV_SAMPLE_FMT_DBL
+	AV_SAMPLE_FMT_NONE int32 = C.AV_SAMPLE_FMT_NONE
+	AV_SAMPLE_FMT_U8   int32 = C.AV_SAMPLE_FMT_U8
+	AV_SAMPLE_FMT_S16  int32 = C.AV_SAMPLE_FMT_S16
+	AV_SAMPLE_FMT_S32  int32 = C.AV_SAMPLE_FMT_S32
+	AV_SAMPLE_FMT_FLT  int32 = C.AV_SAMPLE_FMT_FLT
+	AV_SAMPLE_FMT_DBL  int32 = C.AV_SAMPLE_FMT_DBL
 
 	AV_SAMPLE_FMT_U8P  int32 = C.AV_SAMPLE_FMT_U8P
 	AV_SAMPLE_FMT_S16P int32 = C.AV_SAMPLE_FMT_S16P
@@ -255,7 +349,7 @@ func (cc *CodecCtx) Open(dict *Dict) error {
 	}
 
 	if averr := C.avcodec_open2(cc.avCodecCtx, cc.codec.avCodec, &avDict); averr < 0 {
-		return errors.New(fmt.Sprintf("Error opening codec '%s:%s', averror: %s", cc.codec.Name(), cc.codec.LongName(), AvError(int(averr))))
+		return newAVError(int(averr), fmt.Sprintf("Error opening codec '%s:%s'", cc.codec.Name(), cc.codec.LongName()), "")
 	}
 
 	cc.opened = true
@@ -346,10 +440,23 @@ func (cc *CodecCtx) TimeBase() AVRational {
 }
 
 func (cc *CodecCtx) ChannelLayout() int {
-	return int(cc.avCodecCtx.channel_layout)
+	return int(C.gmf_codec_ctx_get_channel_layout(cc.avCodecCtx))
 }
 func (cc *CodecCtx) SetChannelLayout(channelLayout int) {
-	cc.avCodecCtx.channel_layout = C.uint64_t(channelLayout)
+	C.gmf_codec_ctx_set_channel_layout(cc.avCodecCtx, C.uint64_t(channelLayout))
+}
+
+// ChLayout returns a copy of the codec context channel layout, it must be freed.
+func (cc *CodecCtx) ChLayout() *ChannelLayout {
+	return channelLayoutFromCodecCtx(cc.avCodecCtx)
+}
+
+func (cc *CodecCtx) SetChLayout(l *ChannelLayout) error {
+	if l == nil {
+		return errors.New("channel layout is not initialized")
+	}
+
+	return l.toCodecCtx(cc.avCodecCtx)
 }
 
 func (cc *CodecCtx) BitRate() int {
@@ -357,7 +464,7 @@ func (cc *CodecCtx) BitRate() int {
 }
 
 func (cc *CodecCtx) Channels() int {
-	return int(cc.avCodecCtx.channels)
+	return int(C.gmf_codec_ctx_get_channels(cc.avCodecCtx))
 }
 
 func (cc *CodecCtx) SetBitRate(val int) *CodecCtx {
@@ -449,7 +556,7 @@ func (cc *CodecCtx) SetHasBframes(val int) *CodecCtx {
 }
 
 func (cc *CodecCtx) SetChannels(val int) *CodecCtx {
-	cc.avCodecCtx.channels = C.int(val)
+	C.gmf_codec_ctx_set_channels(cc.avCodecCtx, C.int(val))
 	return cc
 }
 
@@ -473,6 +580,17 @@ func (cc *CodecCtx) SelectChannelLayout() int {
 	return int(C.select_channel_layout(cc.codec.avCodec))
 }
 
+// SelectChLayout returns the supported layout with the most channels, stereo if the codec
+// does not declare supported layouts.
+func (cc *CodecCtx) SelectChLayout() *ChannelLayout {
+	l, err := NewChannelLayout(uint64(cc.SelectChannelLayout()))
+	if err != nil {
+		return NewDefaultChannelLayout(2)
+	}
+
+	return l
+}
+
 func (cc *CodecCtx) FlushBuffers() {
 	C.avcodec_flush_buffers(cc.avCodecCtx)
 }
@@ -546,13 +664,17 @@ func (cc *CodecCtx) GetSampleFmtName() string {
 }
 
 func (cc *CodecCtx) GetChannelLayoutName() string {
-	str := C.GoString(C.gmf_get_channel_layout_name(C.int(cc.Channels()), C.int(cc.ChannelLayout())))
+	cstr := C.gmf_get_channel_layout_name(C.int(cc.Channels()), C.uint64_t(cc.ChannelLayout()))
+	if cstr == nil {
+		return ""
+	}
+	defer C.av_free(unsafe.Pointer(cstr))
 
-	return str
+	return C.GoString(cstr)
 }
 
 func (this *CodecCtx) GetDefaultChannelLayout(ac int) int {
-	return int(C.av_get_default_channel_layout(C.int(ac)))
+	return int(C.gmf_default_channel_layout(C.int(ac)))
 }
 
 func (cc *CodecCtx) GetBitsPerSample() int {
@@ -586,7 +708,7 @@ func (cc *CodecCtx) Decode(pkt *Packet) ([]*Frame, error) {
 		frame := NewFrame()
 
 		ret = int(C.avcodec_receive_frame(cc.avCodecCtx, frame.avFrame))
-		if AvErrno(ret) == syscall.EAGAIN || ret == AVERROR_EOF {
+		if ret == AVERROR_EAGAIN || ret == AVERROR_EOF {
 			frame.Free()
 			break
 		} else if ret < 0 {
@@ -600,6 +722,9 @@ func (cc *CodecCtx) Decode(pkt *Packet) ([]*Frame, error) {
 	return result, nil
 }
 
+// Encode sends the frames to the encoder and returns all the packets it outputs.
+// Nil frame, or empty frames with drain >= 0, drains the encoder. The frames are
+// always freed, even on error, the packets encoded before the error are freed as well.
 func (cc *CodecCtx) Encode(frames []*Frame, drain int) ([]*Packet, error) {
 	var (
 		ret    int
@@ -610,22 +735,28 @@ func (cc *CodecCtx) Encode(frames []*Frame, drain int) ([]*Packet, error) {
 		frames = append(frames, nil)
 	}
 
-	for _, frame := range frames {
+	for i, frame := range frames {
 		if frame == nil {
 			ret = int(C.avcodec_send_frame(cc.avCodecCtx, nil))
 		} else {
 			ret = int(C.avcodec_send_frame(cc.avCodecCtx, frame.avFrame))
 		}
 		if ret < 0 {
+			freeFrames(frames[i:])
+			freePackets(result)
 			return nil, AvError(ret)
 		}
 
 		for {
 			pkt := NewPacket()
-			ret = int(C.avcodec_receive_packet(cc.avCodecCtx, &pkt.avPacket))
-			if ret < 0 {
+			if err := cc.ReceivePacket(pkt); err != nil {
 				pkt.Free()
-				break
+				if isAVError(err, AVERROR_EAGAIN) || isEOF(err) {
+					break
+				}
+				freeFrames(frames[i:])
+				freePackets(result)
+				return nil, err
 			}
 
 			result = append(result, pkt)
@@ -638,27 +769,108 @@ func (cc *CodecCtx) Encode(frames []*Frame, drain int) ([]*Packet, error) {
 	return result, nil
 }
 
+// DecodeFrame sends the packet to the decoder and receives a single frame.
+// ErrAgain is returned, if the decoder needs more packets for the frame,
+// ErrEOF if it is drained.
+func (cc *CodecCtx) DecodeFrame(pkt *Packet) (*Frame, error) {
+	if err := cc.SendPacket(pkt); err != nil {
+		return nil, err
+	}
+
+	frame := NewFrame()
+
+	if err := cc.ReceiveFrame(frame); err != nil {
+		frame.Free()
+		return nil, err
+	}
+
+	return frame, nil
+}
+
+// Decode2 is DecodeFrame returning the raw error code.
+//
+// Deprecated: the code can't be checked with errors.Is, use DecodeFrame.
 func (cc *CodecCtx) Decode2(pkt *Packet) (*Frame, int) {
-	var (
-		ret int
-	)
+	frame, err := cc.DecodeFrame(pkt)
+	if err != nil {
+		if e, ok := err.(*AVError); ok {
+			return nil, e.Code
+		}
+		return nil, AVERROR_EXTERNAL
+	}
+
+	return frame, 0
+}
+
+// SendPacket sends the packet to the decoder, nil packet starts draining.
+// The decoder makes its own reference to the packet data, so the packet
+// is still owned by caller and may be reused after Free. ErrAgain is returned,
+// if decoded frames must be received first, ErrEOF if the decoder is drained.
+func (cc *CodecCtx) SendPacket(pkt *Packet) error {
+	var ret int
 
 	if pkt == nil {
 		ret = int(C.avcodec_send_packet(cc.avCodecCtx, nil))
 	} else {
 		ret = int(C.avcodec_send_packet(cc.avCodecCtx, &pkt.avPacket))
 	}
+
 	if ret < 0 {
-		return nil, ret
+		return AvError(ret)
 	}
 
-	frame := NewFrame()
+	return nil
+}
 
-	if ret = int(C.avcodec_receive_frame(cc.avCodecCtx, frame.avFrame)); ret < 0 {
-		return nil, ret
+// ReceiveFrame receives decoded frame into the frame supplied by caller,
+// previous data of the frame is unreferenced, so a single frame may be reused
+// for the whole stream. ErrAgain is returned, if more packets must be sent,
+// ErrEOF if the decoder is drained.
+func (cc *CodecCtx) ReceiveFrame(frame *Frame) error {
+	if frame == nil || frame.avFrame == nil {
+		return errors.New("Unable to receive frame into nil frame")
 	}
 
-	return frame, 0
+	if ret := int(C.avcodec_receive_frame(cc.avCodecCtx, frame.avFrame)); ret < 0 {
+		return AvError(ret)
+	}
+
+	return nil
+}
+
+// SendFrame sends the frame to the encoder, nil frame starts draining.
+// Unlike Encode, the frame isn't freed, it's still owned by caller.
+// ErrAgain is returned, if encoded packets must be received first,
+// ErrEOF if the encoder is drained.
+func (cc *CodecCtx) SendFrame(frame *Frame) error {
+	var ret int
+
+	if frame == nil {
+		ret = int(C.avcodec_send_frame(cc.avCodecCtx, nil))
+	} else {
+		ret = int(C.avcodec_send_frame(cc.avCodecCtx, frame.avFrame))
+	}
+
+	if ret < 0 {
+		return AvError(ret)
+	}
+
+	return nil
+}
+
+// ReceivePacket receives encoded packet into the packet supplied by caller,
+// previous data of the packet is unreferenced. ErrAgain is returned, if more
+// frames must be sent, ErrEOF if the encoder is drained.
+func (cc *CodecCtx) ReceivePacket(pkt *Packet) error {
+	if pkt == nil {
+		return errors.New("Unable to receive packet into nil packet")
+	}
+
+	if ret := int(C.avcodec_receive_packet(cc.avCodecCtx, &pkt.avPacket)); ret < 0 {
+		return AvError(ret)
+	}
+
+	return nil
 }
 
 func (cc *CodecCtx) SelectSampleFmt() int32 {
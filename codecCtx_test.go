@@ -1,6 +1,7 @@
 package gmf
 
 import (
+	"io"
 	"log"
 	"testing"
 )
@@ -58,3 +59,211 @@ func TestCodecCtx(t *testing.T) {
 
 	Release(cc)
 }
+
+func TestCodecCtxSendReceive(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+	dec := ist.CodecCtx()
+
+	// a single frame is reused for all the packets
+	frame := NewFrame()
+	defer frame.Free()
+
+	decoded := 0
+
+	receive := func() {
+		for {
+			err := dec.ReceiveFrame(frame)
+			if isAVError(err, AVERROR_EAGAIN) || isEOF(err) {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if frame.Width() != 320 || frame.Height() != 200 {
+				t.Fatalf("Expected 320x200 frame, %dx%d got\n", frame.Width(), frame.Height())
+			}
+			decoded++
+		}
+	}
+
+	for {
+		pkt, err := inputCtx.GetNextPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = dec.SendPacket(pkt)
+		pkt.Free()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		receive()
+	}
+
+	if err := dec.SendPacket(nil); err != nil {
+		t.Fatal(err)
+	}
+	receive()
+
+	if decoded != 25 {
+		t.Fatalf("Expected %d decoded frames, %d got\n", 25, decoded)
+	}
+
+	if err := dec.SendPacket(nil); !isEOF(err) {
+		t.Fatalf("Expected ErrEOF sending to the drained decoder, '%v' got\n", err)
+	}
+
+	codec, err := FindEncoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewCodecCtx(codec)
+	defer enc.Free()
+
+	enc.SetTimeBase(AVR{1, 25}).SetDimension(160, 120).SetPixFmt(AV_PIX_FMT_YUV420P).SetMaxBFrames(0)
+
+	if err := enc.Open(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enc.ReceivePacket(NewPacket()); !isAVError(err, AVERROR_EAGAIN) {
+		t.Fatalf("Expected ErrAgain receiving from the empty encoder, '%v' got\n", err)
+	}
+
+	pkt := NewPacket()
+	defer pkt.Free()
+
+	encoded, i := 0, 0
+
+	for f := range GenSyntVideoN(10, 160, 120, AV_PIX_FMT_YUV420P) {
+		f.SetPts(int64(i))
+		i++
+
+		if err := enc.SendFrame(f); err != nil {
+			t.Fatal(err)
+		}
+
+		// the frame is still owned by caller
+		if f.Width() != 160 {
+			t.Fatalf("Expected the frame to be kept after SendFrame\n")
+		}
+		f.Free()
+
+		for enc.ReceivePacket(pkt) == nil {
+			encoded++
+		}
+	}
+
+	if err := enc.SendFrame(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		err := enc.ReceivePacket(pkt)
+		if isEOF(err) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded++
+	}
+
+	if encoded != 10 {
+		t.Fatalf("Expected %d encoded packets, %d got\n", 10, encoded)
+	}
+}
+
+func TestCodecCtxDecodeFrame(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	dec := assert(inputCtx.GetStream(0)).(*Stream).CodecCtx()
+
+	decoded := 0
+
+	for {
+		pkt, err := inputCtx.GetNextPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frame, err := dec.DecodeFrame(pkt)
+		pkt.Free()
+		if isAVError(err, AVERROR_EAGAIN) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frame.Free()
+		decoded++
+	}
+
+	if decoded == 0 {
+		t.Fatalf("Expected decoded frames\n")
+	}
+
+	if _, code := dec.Decode2(nil); code != AVERROR_EOF && code != AVERROR_EAGAIN && code != 0 {
+		t.Fatalf("Expected EOF, EAGAIN or a frame draining, code %d got\n", code)
+	}
+}
+
+func TestCodecCtxEncodeError(t *testing.T) {
+	codec, err := FindEncoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewCodecCtx(codec)
+	defer enc.Free()
+
+	enc.SetTimeBase(AVR{1, 25}).SetDimension(160, 120).SetPixFmt(AV_PIX_FMT_YUV420P).SetMaxBFrames(0)
+
+	if err := enc.Open(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := enc.Encode(nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := make([]*Frame, 0)
+	for f := range GenSyntVideoN(3, 160, 120, AV_PIX_FMT_YUV420P) {
+		frames = append(frames, f)
+	}
+
+	// the drained encoder doesn't accept frames anymore
+	pkts, err := enc.Encode(frames, -1)
+	if err == nil {
+		t.Fatalf("Expected an error encoding to the drained encoder\n")
+	}
+	if pkts != nil {
+		t.Fatalf("Expected no packets on error, %d got\n", len(pkts))
+	}
+
+	for i, f := range frames {
+		if f.avFrame != nil {
+			t.Fatalf("Expected frame %d to be freed on error\n", i)
+		}
+	}
+}
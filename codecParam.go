@@ -43,6 +43,11 @@ func (cp *CodecParameters) GetHeight() int {
 	return int(cp.avCodecParameters.height)
 }
 
+// ChLayout returns a copy of the channel layout, it must be freed.
+func (cp *CodecParameters) ChLayout() *ChannelLayout {
+	return channelLayoutFromCodecPar(cp.avCodecParameters)
+}
+
 func (cp *CodecParameters) FromContext(cc *CodecCtx) error {
 	ret := int(C.avcodec_parameters_from_context(cp.avCodecParameters, cc.avCodecCtx))
 	if ret < 0 {
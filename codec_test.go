@@ -63,3 +63,84 @@ func TestFindByName(t *testing.T) {
 
 	log.Printf("Found %s, %s\n", c.Name(), c.LongName())
 }
+
+func TestCodecCapabilities(t *testing.T) {
+	enc, err := FindEncoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !enc.IsEncoder() || enc.IsDecoder() || enc.WrapperName() != "" {
+		t.Fatalf("Expected native encoder, encoder %v, wrapper '%s' got\n", enc.IsEncoder(), enc.WrapperName())
+	}
+
+	if !enc.SupportsPixFmt(AV_PIX_FMT_YUV420P) || enc.SupportsPixFmt(AV_PIX_FMT_RGB24) {
+		t.Fatalf("Expected only yuv420p to be supported, %v got\n", enc.PixFmts())
+	}
+
+	if !enc.HasCapability(AV_CODEC_CAP_SLICE_THREADS) || enc.HasCapability(AV_CODEC_CAP_HARDWARE) {
+		t.Fatalf("Unexpected capabilities 0x%x\n", enc.Capabilities())
+	}
+
+	dec, err := FindDecoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := dec.Profiles()
+	if len(profiles) == 0 || profiles[0].Name == "" {
+		t.Fatalf("Expected mpeg4 profiles, %v got\n", profiles)
+	}
+
+	aac, err := FindEncoder("aac")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !aac.SupportsSampleFmt(AV_SAMPLE_FMT_FLTP) || aac.SupportsSampleFmt(AV_SAMPLE_FMT_S16) {
+		t.Fatalf("Expected only fltp to be supported, %v got\n", aac.SampleFmts())
+	}
+
+	if !aac.SupportsSampleRate(48000) || aac.SupportsSampleRate(12345) {
+		t.Fatalf("Unexpected sample rates %v\n", aac.SampleRates())
+	}
+
+	layouts := aac.ChannelLayouts()
+	if len(layouts) == 0 {
+		t.Fatalf("Expected aac channel layouts\n")
+	}
+
+	for _, l := range layouts {
+		if !l.IsValid() {
+			t.Fatalf("Expected valid layout, %s got\n", l)
+		}
+		l.Free()
+	}
+}
+
+func TestCodecIterator(t *testing.T) {
+	encoders, decoders, found := 0, 0, false
+
+	it := NewCodecIterator()
+	for it.Next() {
+		codec := it.Codec()
+
+		if codec.IsEncoder() {
+			encoders++
+		}
+		if codec.IsDecoder() {
+			decoders++
+		}
+		if codec.IsEncoder() && codec.Name() == "mpeg4" {
+			found = true
+		}
+	}
+
+	if encoders == 0 || decoders == 0 || !found {
+		t.Fatalf("Expected mpeg4 among %d encoders and %d decoders\n", encoders, decoders)
+	}
+
+	if it.Next() || it.Codec() != nil {
+		t.Fatalf("Expected iterator to stay at the end\n")
+	}
+}
@@ -53,6 +53,20 @@ func NewDict(pairs []Pair) *Dict {
 	return this
 }
 
+// newDictFromMap creates dictionary with all the m entries.
+func newDictFromMap(m map[string]string) (*Dict, error) {
+	d := &Dict{dict: nil}
+
+	for key, value := range m {
+		if err := d.Set(key, value, 0); err != nil {
+			d.Free()
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
 func (d *Dict) Count() int {
 	if d.dict == nil {
 		return 0
@@ -116,6 +130,56 @@ func (d *Dict) Iterator() <-chan DictEntry {
 	return i
 }
 
+// Map returns a copy of all the dictionary entries.
+func (d *Dict) Map() map[string]string {
+	result := make(map[string]string, d.Count())
+
+	if d.dict == nil {
+		return result
+	}
+
+	emptyString := C.CString("")
+	defer C.free(unsafe.Pointer(emptyString))
+
+	var entry *C.struct_AVDictionaryEntry
+
+	for {
+		if entry = C.av_dict_get(d.dict, emptyString, entry, AV_DICT_IGNORE_SUFFIX); entry == nil {
+			break
+		}
+
+		result[C.GoString(entry.key)] = C.GoString(entry.value)
+	}
+
+	return result
+}
+
+// copyTo merges all the entries into dst, existing dst entries are overwritten.
+func (d *Dict) copyTo(dst **C.struct_AVDictionary) error {
+	if d.dict == nil {
+		return nil
+	}
+
+	if ret := C.av_dict_copy(dst, d.dict, 0); int(ret) < 0 {
+		return AvError(int(ret))
+	}
+
+	return nil
+}
+
+// replaceDict frees dictionary pointed by dst and sets it to the m entries.
+func replaceDict(dst **C.struct_AVDictionary, m map[string]string) error {
+	d, err := newDictFromMap(m)
+	if err != nil {
+		return err
+	}
+
+	C.av_dict_free(dst)
+	*dst = d.dict
+
+	return nil
+}
+
 func (d *Dict) Dump() {
 	if d.Count() < 0 {
 		return
@@ -0,0 +1,133 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavcodec
+
+#include "libavformat/avformat.h"
+#include "libavcodec/avcodec.h"
+
+*/
+import "C"
+
+import (
+	"strings"
+)
+
+// Disposition is a set of AV_DISPOSITION_* flags of the stream.
+type Disposition int
+
+const (
+	AV_DISPOSITION_DEFAULT          Disposition = C.AV_DISPOSITION_DEFAULT
+	AV_DISPOSITION_DUB              Disposition = C.AV_DISPOSITION_DUB
+	AV_DISPOSITION_ORIGINAL         Disposition = C.AV_DISPOSITION_ORIGINAL
+	AV_DISPOSITION_COMMENT          Disposition = C.AV_DISPOSITION_COMMENT
+	AV_DISPOSITION_LYRICS           Disposition = C.AV_DISPOSITION_LYRICS
+	AV_DISPOSITION_KARAOKE          Disposition = C.AV_DISPOSITION_KARAOKE
+	AV_DISPOSITION_FORCED           Disposition = C.AV_DISPOSITION_FORCED
+	AV_DISPOSITION_HEARING_IMPAIRED Disposition = C.AV_DISPOSITION_HEARING_IMPAIRED
+	AV_DISPOSITION_VISUAL_IMPAIRED  Disposition = C.AV_DISPOSITION_VISUAL_IMPAIRED
+	AV_DISPOSITION_CLEAN_EFFECTS    Disposition = C.AV_DISPOSITION_CLEAN_EFFECTS
+	AV_DISPOSITION_ATTACHED_PIC     Disposition = C.AV_DISPOSITION_ATTACHED_PIC
+	AV_DISPOSITION_TIMED_THUMBNAILS Disposition = C.AV_DISPOSITION_TIMED_THUMBNAILS
+	AV_DISPOSITION_CAPTIONS         Disposition = C.AV_DISPOSITION_CAPTIONS
+	AV_DISPOSITION_DESCRIPTIONS     Disposition = C.AV_DISPOSITION_DESCRIPTIONS
+	AV_DISPOSITION_METADATA         Disposition = C.AV_DISPOSITION_METADATA
+	AV_DISPOSITION_DEPENDENT        Disposition = C.AV_DISPOSITION_DEPENDENT
+	AV_DISPOSITION_STILL_IMAGE      Disposition = C.AV_DISPOSITION_STILL_IMAGE
+)
+
+// Names are the same as used by ffmpeg -disposition option and ffprobe.
+var dispositionNames = []struct {
+	flag Disposition
+	name string
+}{
+	{AV_DISPOSITION_DEFAULT, "default"},
+	{AV_DISPOSITION_DUB, "dub"},
+	{AV_DISPOSITION_ORIGINAL, "original"},
+	{AV_DISPOSITION_COMMENT, "comment"},
+	{AV_DISPOSITION_LYRICS, "lyrics"},
+	{AV_DISPOSITION_KARAOKE, "karaoke"},
+	{AV_DISPOSITION_FORCED, "forced"},
+	{AV_DISPOSITION_HEARING_IMPAIRED, "hearing_impaired"},
+	{AV_DISPOSITION_VISUAL_IMPAIRED, "visual_impaired"},
+	{AV_DISPOSITION_CLEAN_EFFECTS, "clean_effects"},
+	{AV_DISPOSITION_ATTACHED_PIC, "attached_pic"},
+	{AV_DISPOSITION_TIMED_THUMBNAILS, "timed_thumbnails"},
+	{AV_DISPOSITION_CAPTIONS, "captions"},
+	{AV_DISPOSITION_DESCRIPTIONS, "descriptions"},
+	{AV_DISPOSITION_METADATA, "metadata"},
+	{AV_DISPOSITION_DEPENDENT, "dependent"},
+	{AV_DISPOSITION_STILL_IMAGE, "still_image"},
+}
+
+// Has checks, if all the flags are set.
+func (d Disposition) Has(flags Disposition) bool {
+	return d&flags == flags
+}
+
+// String returns flag names joined by "+", e.g. "default+forced".
+func (d Disposition) String() string {
+	names := make([]string, 0)
+
+	for _, n := range dispositionNames {
+		if d.Has(n.flag) {
+			names = append(names, n.name)
+		}
+	}
+
+	return strings.Join(names, "+")
+}
+
+// Discard is AVDiscard, a level of packets dropped by the demuxer.
+type Discard int
+
+const (
+	AVDISCARD_NONE     Discard = C.AVDISCARD_NONE
+	AVDISCARD_DEFAULT  Discard = C.AVDISCARD_DEFAULT
+	AVDISCARD_NONREF   Discard = C.AVDISCARD_NONREF
+	AVDISCARD_BIDIR    Discard = C.AVDISCARD_BIDIR
+	AVDISCARD_NONINTRA Discard = C.AVDISCARD_NONINTRA
+	AVDISCARD_NONKEY   Discard = C.AVDISCARD_NONKEY
+	AVDISCARD_ALL      Discard = C.AVDISCARD_ALL
+)
+
+func (s *Stream) Disposition() Disposition {
+	return Disposition(s.avStream.disposition)
+}
+
+// SetDisposition replaces all the stream disposition flags. It should be
+// called before the header is written, e.g. to mark the default audio track.
+func (s *Stream) SetDisposition(d Disposition) *Stream {
+	s.avStream.disposition = C.int(d)
+	return s
+}
+
+func (s *Stream) AddDisposition(flags Disposition) *Stream {
+	return s.SetDisposition(s.Disposition() | flags)
+}
+
+func (s *Stream) RemoveDisposition(flags Disposition) *Stream {
+	return s.SetDisposition(s.Disposition() &^ flags)
+}
+
+func (s *Stream) IsDefault() bool {
+	return s.Disposition().Has(AV_DISPOSITION_DEFAULT)
+}
+
+func (s *Stream) IsAttachedPic() bool {
+	return s.Disposition().Has(AV_DISPOSITION_ATTACHED_PIC)
+}
+
+func (s *Stream) Discard() Discard {
+	return Discard(s.avStream.discard)
+}
+
+// SetDiscard sets the level of packets, which are dropped by the demuxer.
+// With AVDISCARD_ALL packets of the stream aren't returned by av_read_frame
+// (most of demuxers don't even read them), so unused streams of large inputs
+// should be discarded right after the input is opened.
+func (s *Stream) SetDiscard(level Discard) *Stream {
+	s.avStream.discard = C.enum_AVDiscard(level)
+	return s
+}
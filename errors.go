@@ -0,0 +1,104 @@
+package gmf
+
+import (
+	"fmt"
+	"io"
+	"syscall"
+)
+
+const (
+	AVERROR_EAGAIN             = -int(syscall.EAGAIN)
+	AVERROR_BUG                = -558323010
+	AVERROR_EXTERNAL           = -542398533
+	AVERROR_INVALIDDATA        = -1094995529
+	AVERROR_BSF_NOT_FOUND      = -1179861752
+	AVERROR_DECODER_NOT_FOUND  = -1128613112
+	AVERROR_ENCODER_NOT_FOUND  = -1129203192
+	AVERROR_DEMUXER_NOT_FOUND  = -1296385272
+	AVERROR_MUXER_NOT_FOUND    = -1481985528
+	AVERROR_FILTER_NOT_FOUND   = -1279870712
+	AVERROR_OPTION_NOT_FOUND   = -1414549496
+	AVERROR_PROTOCOL_NOT_FOUND = -1330794744
+	AVERROR_STREAM_NOT_FOUND   = -1381258232
+)
+
+// Sentinel errors, use errors.Is to check the error returned by the library.
+// ErrEOF also matches io.EOF.
+var (
+	ErrEOF              error = &AVError{Code: AVERROR_EOF}
+	ErrAgain            error = &AVError{Code: AVERROR_EAGAIN}
+	ErrExit             error = &AVError{Code: AVERROR_EXIT}
+	ErrBug              error = &AVError{Code: AVERROR_BUG}
+	ErrExternal         error = &AVError{Code: AVERROR_EXTERNAL}
+	ErrInvalidData      error = &AVError{Code: AVERROR_INVALIDDATA}
+	ErrBsfNotFound      error = &AVError{Code: AVERROR_BSF_NOT_FOUND}
+	ErrDecoderNotFound  error = &AVError{Code: AVERROR_DECODER_NOT_FOUND}
+	ErrEncoderNotFound  error = &AVError{Code: AVERROR_ENCODER_NOT_FOUND}
+	ErrDemuxerNotFound  error = &AVError{Code: AVERROR_DEMUXER_NOT_FOUND}
+	ErrMuxerNotFound    error = &AVError{Code: AVERROR_MUXER_NOT_FOUND}
+	ErrFilterNotFound   error = &AVError{Code: AVERROR_FILTER_NOT_FOUND}
+	ErrOptionNotFound   error = &AVError{Code: AVERROR_OPTION_NOT_FOUND}
+	ErrProtocolNotFound error = &AVError{Code: AVERROR_PROTOCOL_NOT_FOUND}
+	ErrStreamNotFound   error = &AVError{Code: AVERROR_STREAM_NOT_FOUND}
+)
+
+// AVError is an error code returned by FFmpeg, together with the failed
+// operation and the url (or filename) it was called for, if any.
+type AVError struct {
+	Code int
+	Op   string
+	URL  string
+}
+
+func newAVError(code int, op, url string) *AVError {
+	return &AVError{Code: code, Op: op, URL: url}
+}
+
+func (e *AVError) Error() string {
+	msg := avStrError(e.Code)
+
+	if e.Op == "" {
+		return msg
+	}
+
+	if e.URL == "" {
+		return fmt.Sprintf("%s: %s", e.Op, msg)
+	}
+
+	return fmt.Sprintf("%s '%s': %s", e.Op, e.URL, msg)
+}
+
+// Is reports whether target is an AVError with the same code,
+// or syscall.Errno the code is made of. AVERROR_EOF also matches io.EOF.
+func (e *AVError) Is(target error) bool {
+	if target == io.EOF {
+		return e.Code == AVERROR_EOF
+	}
+
+	switch t := target.(type) {
+	case *AVError:
+		return t.Code == e.Code
+	case syscall.Errno:
+		return e.Code == -int(t)
+	}
+
+	return false
+}
+
+// isAVError reports whether err is an AVError with the code. Unlike errors.Is,
+// it is available in go1.12, so the library uses it to check its own errors.
+func isAVError(err error, code int) bool {
+	e, ok := err.(*AVError)
+
+	return ok && e.Code == code
+}
+
+// isEOF reports whether err is the end of the input, ErrEOF or io.EOF.
+func isEOF(err error) bool {
+	return err == io.EOF || isAVError(err, AVERROR_EOF)
+}
+
+// Errno returns the POSIX error number, if the code is made by AVERROR(errno).
+func (e *AVError) Errno() syscall.Errno {
+	return AvErrno(e.Code)
+}
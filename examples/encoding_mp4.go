@@ -82,8 +82,8 @@ func audio(outputCtx *FmtCtx, output chan *Packet) *Stream {
 
 	/// resample
 	options := []*Option{
-		{"in_channel_layout", cc.ChannelLayout()},
-		{"out_channel_layout", occ.ChannelLayout()},
+		{"in_chlayout", cc.ChLayout()},
+		{"out_chlayout", occ.ChLayout()},
 		{"in_sample_rate", cc.SampleRate()},
 		{"out_sample_rate", occ.SampleRate()},
 		{"in_sample_fmt", SampleFmt(cc.SampleFmt())},
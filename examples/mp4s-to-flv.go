@@ -46,12 +46,14 @@ func addStream(name string, oc *gmf.FmtCtx, ist *gmf.Stream) (int, int, error) {
 	}
 
 	if cc.Type() == gmf.AVMEDIA_TYPE_AUDIO {
+		layout := cc.SelectChLayout()
+		defer layout.Free()
+
 		options = append(
 			[]gmf.Option{
 				{Key: "time_base", Val: ist.CodecCtx().TimeBase().AVR()},
 				{Key: "ar", Val: ist.CodecCtx().SampleRate()},
-				{Key: "ac", Val: ist.CodecCtx().Channels()},
-				{Key: "channel_layout", Val: cc.SelectChannelLayout()},
+				{Key: "ch_layout", Val: layout},
 			},
 		)
 
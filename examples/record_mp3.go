@@ -83,8 +83,8 @@ func main() {
 
 	/// resample
 	options := []*Option{
-		{"in_channel_count", cc.Channels()},
-		{"out_channel_count", cc.Channels()},
+		{"in_chlayout", cc.ChLayout()},
+		{"out_chlayout", occ.ChLayout()},
 		{"in_sample_rate", cc.SampleRate()},
 		{"out_sample_rate", cc.SampleRate()},
 		{"in_sample_fmt", SampleFmt(cc.SampleFmt())},
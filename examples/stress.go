@@ -715,13 +715,18 @@ func resampleStep() {
 	options = []gmf.Option{
 		{Key: "time_base", Val: gmf.AVR{Num: 1, Den: 22050}},
 		{Key: "ar", Val: 22050},
-		{Key: "ac", Val: 2},
-		{Key: "channel_layout", Val: 3},
 	}
 
 	cc.SetSampleFmt(8)
 	cc.SetOptions(options)
 
+	stereo := gmf.NewDefaultChannelLayout(2)
+	defer stereo.Free()
+
+	if err := cc.SetChLayout(stereo); err != nil {
+		log.Fatalln(err)
+	}
+
 	if err := cc.Open(nil); err != nil {
 		log.Fatalln(err)
 	}
@@ -760,16 +765,21 @@ func resampleStep() {
 	if ost.SwrCtx == nil {
 		icc := ist.CodecCtx()
 		occ := ost.CodecCtx()
+
+		inLayout, outLayout := icc.ChLayout(), occ.ChLayout()
+		defer inLayout.Free()
+		defer outLayout.Free()
+
 		options := []*gmf.Option{
-			{Key: "in_channel_layout", Val: icc.ChannelLayout()},
-			{Key: "out_channel_layout", Val: occ.ChannelLayout()},
+			{Key: "in_chlayout", Val: inLayout},
+			{Key: "out_chlayout", Val: outLayout},
 			{Key: "in_sample_rate", Val: icc.SampleRate()},
 			{Key: "out_sample_rate", Val: occ.SampleRate()},
 			{Key: "in_sample_fmt", Val: gmf.SampleFormat(icc.SampleFmt())},
 			{Key: "out_sample_fmt", Val: gmf.SampleFormat(gmf.AV_SAMPLE_FMT_FLTP)},
 		}
 
-		if ost.SwrCtx, err = gmf.NewSwrCtx(options, occ.Channels(), occ.SampleFmt()); err != nil {
+		if ost.SwrCtx, err = gmf.NewSwrCtxWithLayout(options, outLayout, occ.SampleFmt()); err != nil {
 			panic(err)
 		}
 		ost.AvFifo = gmf.NewAVAudioFifo(icc.SampleFmt(), ist.CodecCtx().Channels(), 1024)
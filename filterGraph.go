@@ -12,10 +12,21 @@ package gmf
 #include <libavutil/pixdesc.h>
 #include <libavutil/display.h>
 
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+#define GMF_CH_LAYOUT_API 1
+#endif
+
 double gmf_get_rotation(AVStream *st)
 {
+#if LIBAVFORMAT_VERSION_MAJOR >= 61
+    const AVPacketSideData *sd = av_packet_side_data_get(st->codecpar->coded_side_data,
+                                                         st->codecpar->nb_coded_side_data,
+                                                         AV_PKT_DATA_DISPLAYMATRIX);
+    uint8_t* displaymatrix = sd ? sd->data : NULL;
+#else
     uint8_t* displaymatrix = av_stream_get_side_data(st,
                                                      AV_PKT_DATA_DISPLAYMATRIX, NULL);
+#endif
     double theta = 0;
     if (displaymatrix)
         theta = -av_display_rotation_get((int32_t*) displaymatrix);
@@ -126,12 +137,24 @@ static char* gmf_choose_sample_rates(AVCodecContext *codecCtx,AVCodec *codec) {
 }
 
 static char* gmf_choose_channel_layouts(AVCodecContext *codecCtx,AVCodec *codec) {
+#ifdef GMF_CH_LAYOUT_API
+    if (codecCtx->ch_layout.nb_channels != 0) {
+        AVChannelLayout ch_layout;
+		char name[16];
+        av_channel_layout_default(&ch_layout, codecCtx->ch_layout.nb_channels);
+        snprintf(name, sizeof(name), "0x%"PRIx64, ch_layout.order == AV_CHANNEL_ORDER_NATIVE ? ch_layout.u.mask : 0);
+        av_channel_layout_uninit(&ch_layout);
+        return av_strdup(name);
+    } else if (codec->ch_layouts) {
+        const AVChannelLayout *p;
+#else
     if (codecCtx->channels != 0) {
 		char name[16];
         snprintf(name, sizeof(name), "0x%"PRIx64, av_get_default_channel_layout(codecCtx->channels));
         return av_strdup(name);
     } else if (codec->channel_layouts) {
         const uint64_t *p;
+#endif
         AVIOContext *s = NULL;
         uint8_t *ret;
         int len;
@@ -140,12 +163,26 @@ static char* gmf_choose_channel_layouts(AVCodecContext *codecCtx,AVCodec *codec)
 			return NULL;
 		}
 
+#ifdef GMF_CH_LAYOUT_API
+        for (p = codec->ch_layouts; p->nb_channels; p++) {
+			char name[16];
+			if (p->order != AV_CHANNEL_ORDER_NATIVE)
+				continue;
+			snprintf(name, sizeof(name), "0x%"PRIx64, p->u.mask);
+            avio_printf(s, "%s|", name);
+        }
+#else
         for (p = codec->channel_layouts; *p != 0; p++) {
 			char name[16];
 			snprintf(name, sizeof(name), "0x%"PRIx64, *p);
             avio_printf(s, "%s|", name);
         }
+#endif
         len = avio_close_dyn_buf(s, &ret);
+        if (len == 0) {
+            av_free(ret);
+            return NULL;
+        }
         ret[len - 1] = 0;
         return (char*)ret;
     } else {
@@ -153,6 +190,16 @@ static char* gmf_choose_channel_layouts(AVCodecContext *codecCtx,AVCodec *codec)
 	}
 }
 
+static void gmf_set_channel_layout_from_sink(AVCodecContext *codecCtx, AVFilterContext *sink) {
+#ifdef GMF_CH_LAYOUT_API
+    av_channel_layout_uninit(&codecCtx->ch_layout);
+    av_buffersink_get_ch_layout(sink, &codecCtx->ch_layout);
+#else
+    codecCtx->channel_layout = av_buffersink_get_channel_layout(sink);
+    codecCtx->channels = av_buffersink_get_channels(sink);
+#endif
+}
+
 static enum AVSampleFormat gmf_int_to_AVSampleFormat(int value) {
 	return value;
 }
@@ -262,6 +309,7 @@ func (fg *FilterGraph) configureGraph(frame *Frame) error {
 
 	var (
 		ret, i  int
+		err     error
 		inputs  *C.AVFilterInOut
 		outputs *C.AVFilterInOut
 	)
@@ -283,9 +331,12 @@ func (fg *FilterGraph) configureGraph(frame *Frame) error {
 	i = 0
 	for cur := inputs; cur != nil; cur = cur.next {
 		if fg.video {
-			fg.configVideoInput(frame, i, cur)
+			err = fg.configVideoInput(frame, i, cur)
 		} else {
-			fg.configAudioInput(frame, i, cur)
+			err = fg.configAudioInput(frame, i, cur)
+		}
+		if err != nil {
+			return err
 		}
 		i++
 	}
@@ -293,9 +344,12 @@ func (fg *FilterGraph) configureGraph(frame *Frame) error {
 	i = 0
 	for cur := outputs; cur != nil; cur = cur.next {
 		if fg.video {
-			fg.configVideoOutput(frame, i, cur)
+			err = fg.configVideoOutput(frame, i, cur)
 		} else {
-			fg.configAudioOutput(frame, i, cur)
+			err = fg.configAudioOutput(frame, i, cur)
+		}
+		if err != nil {
+			return err
 		}
 		i++
 	}
@@ -376,8 +430,7 @@ func (fg *FilterGraph) initEncoderContext(idx int) error {
 			C.av_get_bytes_per_sample(encCtx.avCodecCtx.sample_fmt)<<3)
 
 		encCtx.avCodecCtx.sample_rate = C.av_buffersink_get_sample_rate(sinkFilterContext)
-		encCtx.avCodecCtx.channel_layout = C.av_buffersink_get_channel_layout(sinkFilterContext)
-		encCtx.avCodecCtx.channels = C.av_buffersink_get_channels(sinkFilterContext)
+		C.gmf_set_channel_layout_from_sink(encCtx.avCodecCtx, sinkFilterContext)
 
 		encCtx.avCodecCtx.time_base = C.av_make_q(1, encCtx.avCodecCtx.sample_rate)
 	}
@@ -440,10 +493,13 @@ func (fg *FilterGraph) configAudioInput(frame *Frame, idx int, in *C.AVFilterInO
 	var args = fmt.Sprintf("time_base=1/%d:sample_rate=%d:sample_fmt=%s", frame.SampleRate(), frame.SampleRate(), C.GoString(sampleFmt))
 	C.av_freep(unsafe.Pointer(&sampleFmt))
 
-	if frame.ChannelLayout() > 0 {
-		args += fmt.Sprintf(":channel_layout=0x%d", frame.ChannelLayout())
-	} else {
-		args += fmt.Sprintf(":channels=%d", frame.Channels())
+	if chLayout := frame.ChLayout(); chLayout != nil {
+		if arg := chLayout.filterArg(); arg != "" {
+			args += ":channel_layout=" + arg
+		} else {
+			args += fmt.Sprintf(":channels=%d", chLayout.NbChannels())
+		}
+		chLayout.Free()
 	}
 
 	var filterContext *C.AVFilterContext
@@ -555,7 +611,7 @@ func (fg *FilterGraph) configAudioOutput(frame *Frame, idx int, out *C.AVFilterI
 	occ := outStream.CodecCtx()
 
 	if occ.Channels() > 0 && occ.ChannelLayout() == 0 {
-		occ.SetChannelLayout(int(C.av_get_default_channel_layout(C.int(occ.Channels()))))
+		occ.SetChannelLayout(occ.GetDefaultChannelLayout(occ.Channels()))
 	}
 
 	/****************************** format ******************************/
@@ -651,7 +707,9 @@ func (fg *FilterGraph) AddFrame(frame *Frame, istIdx int, flag int) error {
 	var ret int
 
 	if fg.filterGraph == nil {
-		fg.configureGraph(frame)
+		if err := fg.configureGraph(frame); err != nil {
+			return err
+		}
 	}
 
 	if istIdx >= len(fg.inFilterCtxs) {
@@ -699,6 +757,28 @@ func (fg *FilterGraph) GetFrame() ([]*Frame, error) {
 	return result, AvError(ret)
 }
 
+// receiveFrame returns the next filtered frame, requesting it from the graph if needed.
+// ErrAgain is returned, if more input frames are needed, ErrEOF after Close.
+func (fg *FilterGraph) receiveFrame() (*Frame, error) {
+	if len(fg.outFilterCtxs) == 0 {
+		return nil, errors.New("Graph not inited")
+	}
+
+	frame := NewFrame()
+
+	if ret := int(C.av_buffersink_get_frame_flags(fg.outFilterCtxs[0], frame.avFrame, 0)); ret < 0 {
+		frame.Free()
+		return nil, AvError(ret)
+	}
+
+	return frame, nil
+}
+
+// sinkTimeBase returns time base of the filtered frames, the graph must be configured.
+func (fg *FilterGraph) sinkTimeBase() AVRational {
+	return AVRational(C.av_buffersink_get_time_base(fg.outFilterCtxs[0]))
+}
+
 func (fg *FilterGraph) Close(istIdx int) error {
 	var ret int
 
@@ -17,6 +17,10 @@ static AVStream* gmf_get_stream(AVFormatContext *ctx, int idx) {
 }
 
 static int gmf_alloc_priv_data(AVFormatContext *s, AVDictionary **options) {
+#if LIBAVFORMAT_VERSION_MAJOR >= 61
+	// priv_data_size is private since FFmpeg 7.0, avformat_open_input allocates priv_data itself
+	return 0;
+#else
 	AVDictionary *tmp = NULL;
 
     if (options)
@@ -38,6 +42,7 @@ static int gmf_alloc_priv_data(AVFormatContext *s, AVDictionary **options) {
 	}
 
 	return 0;
+#endif
 }
 
 static char *gmf_sprintf_sdp(AVFormatContext *ctx) {
@@ -49,12 +54,12 @@ static char *gmf_sprintf_sdp(AVFormatContext *ctx) {
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
-	"syscall"
 	"time"
 	"unsafe"
 )
@@ -94,6 +99,7 @@ type FmtCtx struct {
 	streams  map[int]*Stream
 	customPb bool
 	isInput  bool
+	ioCtx    *ioContext
 }
 
 func init() {
@@ -237,6 +243,34 @@ func NewInputCtxWithOption(filename string, options ...*Option) (*FmtCtx, error)
 	return ctx, nil
 }
 
+// NewInputCtxWithContext opens input like NewInputCtxWithOption, the opening
+// is aborted with ctx.Err() when ctx is cancelled or its deadline exceeds.
+func NewInputCtxWithContext(ctx context.Context, filename string, options ...*Option) (*FmtCtx, error) {
+	fmtCtx, err := NewCtx(options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var iDict *Dict = nil
+
+	for _, option := range options {
+		if strings.Compare(option.Key, "input_options") == 0 {
+			iDict = option.Val.(*Dict)
+		}
+	}
+
+	if err := fmtCtx.OpenInputWithContext(ctx, filename, iDict); err != nil {
+		fmtCtx.Free()
+		return nil, err
+	}
+
+	fmtCtx.filename = filename
+	fmtCtx.isInput = true
+
+	return fmtCtx, nil
+}
+
 func NewInputCtxWithFormatName(filename, format string) (*FmtCtx, error) {
 	ctx, err := NewCtx()
 
@@ -282,11 +316,11 @@ func (this *FmtCtx) OpenInputWithOption(filename string, dict *Dict) error {
 	}
 
 	if averr := C.avformat_open_input(&this.avCtx, cFilename, nil, &vDict.dict); averr < 0 {
-		return errors.New(fmt.Sprintf("Error opening input '%s': %s", filename, AvError(int(averr))))
+		return this.ioError(int(averr), "Error opening input", filename)
 	}
 
 	if averr := C.avformat_find_stream_info(this.avCtx, nil); averr < 0 {
-		return errors.New(fmt.Sprintf("Unable to find stream info: %s", AvError(int(averr))))
+		return this.ioError(int(averr), "Unable to find stream info", filename)
 	}
 
 	return nil
@@ -296,6 +330,26 @@ func (this *FmtCtx) OpenInput(filename string) error {
 	return this.OpenInputWithOption(filename, nil)
 }
 
+// OpenInputWithContext is the same as OpenInputWithOption, but a blocked
+// open (e.g. unreachable network source) is aborted when ctx is done.
+func (this *FmtCtx) OpenInputWithContext(ctx context.Context, filename string, dict *Dict) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unbind := this.bindInterrupt(ctx)
+	defer unbind()
+
+	if err := this.OpenInputWithOption(filename, dict); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (this *FmtCtx) AddStreamWithCodeCtx(codeCtx *CodecCtx) (*Stream, error) {
 	var ost *Stream
 
@@ -313,8 +367,31 @@ func (this *FmtCtx) AddStreamWithCodeCtx(codeCtx *CodecCtx) (*Stream, error) {
 	return ost, nil
 }
 
-func (this *FmtCtx) WriteTrailer() {
-	C.av_write_trailer(this.avCtx)
+// WriteTrailer drains stream bitstream filters and writes the trailer.
+func (this *FmtCtx) WriteTrailer() error {
+	for _, st := range this.streams {
+		if len(st.bsfs) == 0 {
+			continue
+		}
+
+		pkts, err := st.filterPacket(nil)
+		if err != nil {
+			return err
+		}
+
+		for i, pkt := range pkts {
+			if err := this.writePacket(pkt); err != nil {
+				freePackets(pkts[i+1:])
+				return err
+			}
+		}
+	}
+
+	if averr := C.av_write_trailer(this.avCtx); averr < 0 {
+		return this.ioError(int(averr), "Unable to write trailer to", this.filename)
+	}
+
+	return nil
 }
 
 func (this *FmtCtx) IsNoFile() bool {
@@ -326,25 +403,56 @@ func (this *FmtCtx) IsGlobalHeader() bool {
 }
 
 func (this *FmtCtx) WriteHeader() error {
-	cfilename := &(this.avCtx.filename[0])
+	cfilename := this.avCtx.url
+	if cfilename == nil {
+		cfilename = C.CString(this.filename)
+		defer C.free(unsafe.Pointer(cfilename))
+	}
 
 	// If NOFILE flag isn't set and we don't use custom IO, open it
 	if !this.IsNoFile() && !this.customPb {
 		if averr := C.avio_open(&this.avCtx.pb, cfilename, C.AVIO_FLAG_WRITE); averr < 0 {
-			return errors.New(fmt.Sprintf("Unable to open '%s': %s", this.filename, AvError(int(averr))))
+			return newAVError(int(averr), "Unable to open", this.filename)
 		}
 	}
 
 	if averr := C.avformat_write_header(this.avCtx, nil); averr < 0 {
-		return errors.New(fmt.Sprintf("Unable to write header to '%s': %s", this.filename, AvError(int(averr))))
+		return this.ioError(int(averr), "Unable to write header to", this.filename)
 	}
 
-	return nil
+	// muxer may change stream time base, bitstream filters must use the new one
+	for _, st := range this.streams {
+		if err := st.resetBitstreamFilters(); err != nil {
+			return err
+		}
+	}
+
+	return this.writeAttachedPictures()
 }
 
 func (this *FmtCtx) WritePacket(p *Packet) error {
+	if st, ok := this.streams[p.StreamIndex()]; ok && len(st.bsfs) > 0 {
+		pkts, err := st.filterPacket(p)
+		if err != nil {
+			return err
+		}
+
+		for i, pkt := range pkts {
+			if err := this.writePacket(pkt); err != nil {
+				freePackets(pkts[i+1:])
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return this.writePacket(p)
+}
+
+func (this *FmtCtx) writePacket(p *Packet) error {
 	if averr := C.av_interleaved_write_frame(this.avCtx, &p.avPacket); averr < 0 {
-		return errors.New(fmt.Sprintf("Unable to write packet to '%s': %s", this.filename, AvError(int(averr))))
+		return this.ioError(int(averr), "Unable to write packet to", this.filename)
 	}
 
 	return nil
@@ -352,7 +460,7 @@ func (this *FmtCtx) WritePacket(p *Packet) error {
 
 func (this *FmtCtx) WritePacketNoBuffer(p *Packet) error {
 	if averr := C.av_write_frame(this.avCtx, &p.avPacket); averr < 0 {
-		return errors.New(fmt.Sprintf("Unable to write packet to '%s': %s", this.filename, AvError(int(averr))))
+		return this.ioError(int(averr), "Unable to write packet to", this.filename)
 	}
 
 	return nil
@@ -372,9 +480,9 @@ func (this *FmtCtx) SetOformat(ofmt *OutputFmt) error {
 
 func (this *FmtCtx) Dump() {
 	if this.ofmt == nil {
-		C.av_dump_format(this.avCtx, 0, &(this.avCtx.filename[0]), 0)
+		C.av_dump_format(this.avCtx, 0, this.avCtx.url, 0)
 	} else {
-		C.av_dump_format(this.avCtx, 0, &(this.avCtx.filename[0]), 1)
+		C.av_dump_format(this.avCtx, 0, this.avCtx.url, 1)
 	}
 }
 
@@ -383,13 +491,14 @@ func (this *FmtCtx) DumpAv() {
 	fmt.Println("flags:", this.avCtx.flags)
 }
 
+// GetNextPacket reads next packet, io.EOF is returned at the end of the input.
 func (this *FmtCtx) GetNextPacket() (*Packet, error) {
 	pkt := NewPacket()
 
 	for {
 		ret := int(C.av_read_frame(this.avCtx, &pkt.avPacket))
 
-		if AvErrno(ret) == syscall.EAGAIN {
+		if ret == AVERROR_EAGAIN {
 			time.Sleep(10000 * time.Microsecond)
 			continue
 		}
@@ -397,7 +506,7 @@ func (this *FmtCtx) GetNextPacket() (*Packet, error) {
 			return nil, io.EOF
 		}
 		if ret < 0 {
-			return nil, AvError(ret)
+			return nil, this.ioError(ret, "Unable to read packet from", this.filename)
 		}
 
 		break
@@ -406,6 +515,48 @@ func (this *FmtCtx) GetNextPacket() (*Packet, error) {
 	return pkt, nil
 }
 
+// ReadPacket reads next packet. Unlike GetNextPacket, it returns ctx.Err()
+// as soon as ctx is done, even if av_read_frame is blocked on a stalled input.
+// ErrEOF, which matches io.EOF as well, is returned at the end of the input.
+func (this *FmtCtx) ReadPacket(ctx context.Context) (*Packet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	unbind := this.bindInterrupt(ctx)
+	defer unbind()
+
+	pkt := NewPacket()
+
+	for {
+		ret := int(C.av_read_frame(this.avCtx, &pkt.avPacket))
+
+		if ret == AVERROR_EAGAIN {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+			continue
+		}
+		if ret < 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if ret == AVERROR_EOF {
+			return nil, ErrEOF
+		}
+		if ret < 0 {
+			return nil, this.ioError(ret, "Unable to read packet from", this.filename)
+		}
+
+		break
+	}
+
+	return pkt, nil
+}
+
+// Deprecated: reading errors are not reported and the goroutine is leaked,
+// if the channel is not read till the end. Use Packets instead.
 func (this *FmtCtx) GetNewPackets() chan *Packet {
 	yield := make(chan *Packet)
 
@@ -477,7 +628,7 @@ func (this *FmtCtx) GetBestStream(typ int32) (*Stream, error) {
 
 func (this *FmtCtx) FindStreamInfo() error {
 	if averr := C.avformat_find_stream_info(this.avCtx, nil); averr < 0 {
-		return errors.New(fmt.Sprintf("unable to find stream info: %s", AvError(int(averr))))
+		return newAVError(int(averr), "unable to find stream info", this.filename)
 	}
 
 	return nil
@@ -541,6 +692,7 @@ func (this *FmtCtx) Free() {
 
 	for _, stream := range this.streams {
 		if stream != nil {
+			stream.freeBitstreamFilters()
 			stream.avFmtCtx = nil
 			Release(stream)
 		}
@@ -549,6 +701,11 @@ func (this *FmtCtx) Free() {
 	if this.avCtx != nil {
 		C.avformat_free_context(this.avCtx)
 	}
+
+	if this.ioCtx != nil {
+		this.ioCtx.Free()
+		this.ioCtx = nil
+	}
 }
 func (this *FmtCtx) Duration() float64 {
 	return float64(this.avCtx.duration) / float64(AV_TIME_BASE)
@@ -568,6 +725,23 @@ func (this *FmtCtx) SetStartTime(val int) *FmtCtx {
 	return this
 }
 
+// Metadata returns a copy of the container level metadata, e.g. title, artist, creation_time.
+func (this *FmtCtx) Metadata() map[string]string {
+	return (&Dict{dict: this.avCtx.metadata}).Map()
+}
+
+// SetMetadata replaces the container level metadata by m. It should be called
+// before WriteHeader, note that some tags (e.g. encoder) are set by the muxer.
+func (this *FmtCtx) SetMetadata(m map[string]string) error {
+	return replaceDict(&this.avCtx.metadata, m)
+}
+
+// CopyMetadata merges metadata of the src context, e.g. input one during remux,
+// into this context metadata.
+func (this *FmtCtx) CopyMetadata(src *FmtCtx) error {
+	return (&Dict{dict: src.avCtx.metadata}).copyTo(&this.avCtx.metadata)
+}
+
 func (this *FmtCtx) TsOffset(stime int) int {
 	// temp solution. see ffmpeg_opt.c:899
 	return (0 - stime)
@@ -608,6 +782,71 @@ func (this *FmtCtx) SeekFrameAt(sec int64, streamIndex int) error {
 	return nil
 }
 
+// SeekAccurate seeks the stream to the keyframe before ts (relative to the stream
+// start), flushes the decoder and decodes up to ts. It returns the first frame,
+// which presentation time is at or after ts, ErrEOF if there is no such frame.
+// Decoding may be continued from the returned frame with the stream CodecCtx,
+// frames decoded from the same packet after the returned one are dropped.
+func (this *FmtCtx) SeekAccurate(streamIndex int, ts time.Duration) (*Frame, error) {
+	ist, err := this.GetStream(streamIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := ist.CodecCtx()
+	if cc == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to open decoder of the stream %d", streamIndex))
+	}
+
+	target := RescaleQ(int64(ts/time.Microsecond), AV_TIME_BASE_Q, ist.TimeBase())
+	if start := ist.GetStartTime(); start != AV_NOPTS_VALUE {
+		target += start
+	}
+
+	if averr := C.avformat_seek_file(this.avCtx, C.int(streamIndex), C.INT64_MIN, C.int64_t(target), C.int64_t(target), 0); averr < 0 {
+		return nil, newAVError(int(averr), "Unable to seek", this.filename)
+	}
+
+	cc.FlushBuffers()
+
+	for {
+		pkt, err := this.GetNextPacket()
+		if err != nil && !isEOF(err) {
+			return nil, err
+		}
+
+		if pkt != nil && pkt.StreamIndex() != streamIndex {
+			pkt.Free()
+			continue
+		}
+
+		// nil packet drains the decoder at the end of the input
+		frames, derr := cc.Decode(pkt)
+		if pkt != nil {
+			pkt.Free()
+		}
+		if derr != nil {
+			return nil, derr
+		}
+
+		for i, frame := range frames {
+			if pts := frame.BestEffortTimestamp(); pts != AV_NOPTS_VALUE && pts >= target {
+				for _, f := range frames[i+1:] {
+					f.Free()
+				}
+
+				return frame, nil
+			}
+
+			frame.Free()
+		}
+
+		if isEOF(err) {
+			return nil, ErrEOF
+		}
+	}
+}
+
 func (this *FmtCtx) SetPb(val *AVIOContext) *FmtCtx {
 	this.avCtx.pb = val.avAVIOContext
 	this.customPb = true
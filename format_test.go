@@ -1,12 +1,17 @@
 package gmf
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"testing"
+	"time"
 )
 
 var (
@@ -139,6 +144,60 @@ func TestPacketsIterator(t *testing.T) {
 	}
 }
 
+func TestPacketIterator(t *testing.T) {
+	for _, readAhead := range []int{0, 4} {
+		inputCtx, err := NewInputCtx(inputSampleFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cnt := 0
+
+		it := inputCtx.Packets(readAhead)
+		for it.Next() {
+			if it.Packet().Size() <= 0 {
+				t.Fatal("Expected size > 0")
+			}
+			it.Packet().Free()
+			cnt++
+		}
+
+		if err := it.Err(); err != nil {
+			t.Fatalf("Expected nil error at EOF, '%v' got\n", err)
+		}
+		if cnt != 25 {
+			t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+		}
+
+		it.Close()
+		inputCtx.Free()
+	}
+}
+
+func TestPacketIteratorClose(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer inputCtx.Free()
+
+	it := inputCtx.Packets(2)
+	if !it.Next() {
+		t.Fatal("Expected at least one packet")
+	}
+	it.Packet().Free()
+
+	it.Close()
+
+	if it.Next() {
+		t.Fatal("Expected no packets after Close")
+	}
+	if it.Err() != nil {
+		t.Fatalf("Expected nil error after Close, '%v' got\n", it.Err())
+	}
+}
+
 func TestGetNextPacket(t *testing.T) {
 	inputCtx, err := NewInputCtx(inputSampleFilename)
 	if err != nil {
@@ -156,6 +215,167 @@ func TestGetNextPacket(t *testing.T) {
 	packet.Free()
 }
 
+func TestGetNextPacketEOF(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	// legacy API returns io.EOF, the new ones ErrEOF
+	for {
+		packet, err := inputCtx.GetNextPacket()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("Expected io.EOF, '%v' got\n", err)
+			}
+			break
+		}
+		packet.Free()
+	}
+
+	if _, err := inputCtx.ReadPacket(context.Background()); err != ErrEOF {
+		t.Fatalf("Expected ErrEOF, '%v' got\n", err)
+	}
+}
+
+func TestReadPacketContext(t *testing.T) {
+	inputCtx, err := NewInputCtxWithContext(context.Background(), inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer inputCtx.Free()
+
+	packet, err := inputCtx.ReadPacket(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Size() <= 0 {
+		t.Fatal("Expected size > 0")
+	}
+	packet.Free()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := inputCtx.ReadPacket(ctx); err != context.Canceled {
+		t.Fatalf("Expected '%v', '%v' got\n", context.Canceled, err)
+	}
+}
+
+func TestOpenInputContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+
+	if _, err := NewInputCtxWithContext(ctx, inputSampleFilename); err != context.DeadlineExceeded {
+		t.Fatalf("Expected '%v', '%v' got\n", context.DeadlineExceeded, err)
+	}
+}
+
+// stalledServer accepts a single tcp connection, sends data and keeps
+// the connection open without sending anything else, till the test ends.
+func stalledServer(t *testing.T, data []byte) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write(data)
+		<-done
+	}()
+
+	return "tcp://" + l.Addr().String(), func() {
+		close(done)
+		l.Close()
+	}
+}
+
+func TestOpenInputContextInterrupt(t *testing.T) {
+	url, stop := stalledServer(t, nil)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	// probing is blocked in av_read_frame on the silent connection
+	if _, err := NewInputCtxWithContext(ctx, url); err != context.Canceled {
+		t.Fatalf("Expected '%v', '%v' got\n", context.Canceled, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected open to be interrupted, it took %v\n", elapsed)
+	}
+}
+
+func TestReadPacketInterrupt(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	url, stop := stalledServer(t, buf.Bytes())
+	defer stop()
+
+	openCtx, cancelOpen := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelOpen()
+
+	// stream info is analyzed within the sent packets
+	inputCtx, err := NewInputCtxWithContext(openCtx, url, &Option{Key: "analyzeduration", Val: 500000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+
+	start := time.Now()
+	cnt := 0
+
+	// the sent packets are read, then av_read_frame is blocked on the stalled connection
+	for {
+		pkt, err := inputCtx.ReadPacket(ctx)
+		if err != nil {
+			if err != context.Canceled {
+				t.Fatalf("Expected '%v', '%v' got\n", context.Canceled, err)
+			}
+			break
+		}
+
+		pkt.Free()
+		cnt++
+	}
+
+	if cnt == 0 {
+		t.Fatalf("Expected packets before the stall\n")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected read to be interrupted, it took %v\n", elapsed)
+	}
+}
+
 var section *io.SectionReader
 
 func customReader() ([]byte, int) {
@@ -245,3 +465,191 @@ func ExampleNewAVIOContext() {
 		p.Free()
 	}
 }
+
+func TestMetadata(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := func(inputCtx *FmtCtx, ist, ost *Stream) {
+		if err := outputCtx.CopyMetadata(inputCtx); err != nil {
+			t.Fatal(err)
+		}
+
+		metadata := outputCtx.Metadata()
+		for key, value := range inputCtx.Metadata() {
+			if metadata[key] != value {
+				t.Fatalf("Expected copied tag %s='%s', '%s' got\n", key, value, metadata[key])
+			}
+		}
+
+		metadata["title"] = "gmf test"
+		if err := outputCtx.SetMetadata(metadata); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ost.CopyMetadata(ist); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ost.SetMetadata(map[string]string{"language": "eng"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if m := ost.Metadata(); len(m) != 1 || m["language"] != "eng" {
+			t.Fatalf("Expected only language tag, %v got\n", m)
+		}
+	}
+
+	if err := remuxSampleWith(t, outputCtx, prepare); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	if title := inputCtx.Metadata()["title"]; title != "gmf test" {
+		t.Fatalf("Expected title 'gmf test', '%s' got\n", title)
+	}
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	if lang := ist.Metadata()["language"]; lang != "eng" {
+		t.Fatalf("Expected language 'eng', '%s' got\n", lang)
+	}
+}
+
+// writeSyntheticClip encodes n frames 25 fps mpeg4 with keyframe every 12 frames,
+// frame pts is its index.
+func writeSyntheticClip(t *testing.T, filename string, n int) {
+	codec, err := FindEncoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputCtx, err := NewOutputCtx(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	cc := NewCodecCtx(codec)
+	if cc == nil {
+		t.Fatal("Unable to allocate codec context")
+	}
+	defer cc.Free()
+
+	cc.SetTimeBase(AVR{1, 25}).SetDimension(160, 120).SetPixFmt(AV_PIX_FMT_YUV420P).SetGopSize(12).SetMaxBFrames(0)
+
+	if outputCtx.IsGlobalHeader() {
+		cc.SetFlag(CODEC_FLAG_GLOBAL_HEADER)
+	}
+
+	if err := cc.Open(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ost := outputCtx.NewStream(codec)
+	if ost == nil {
+		t.Fatal("Unable to create stream")
+	}
+	ost.DumpContexCodec(cc)
+	ost.SetTimeBase(AVR{1, 25})
+
+	if err := outputCtx.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(pkts []*Packet) {
+		for _, pkt := range pkts {
+			RescaleTs(pkt, AVR{1, 25}.AVRational(), ost.TimeBase())
+			pkt.SetStreamIndex(ost.Index())
+
+			err := outputCtx.WritePacket(pkt)
+			pkt.Free()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	i := 0
+
+	for frame := range GenSyntVideoN(n, 160, 120, AV_PIX_FMT_YUV420P) {
+		frame.SetPts(int64(i))
+		i++
+
+		pkts, err := cc.Encode([]*Frame{frame}, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		write(pkts)
+	}
+
+	pkts, err := cc.Encode(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(pkts)
+
+	if err := outputCtx.WriteTrailer(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeekAccurate(t *testing.T) {
+	f, err := ioutil.TempFile("", "gmf-seek-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	writeSyntheticClip(t, f.Name(), 100)
+
+	inputCtx, err := NewInputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	// not ordered on purpose, backward seeks must work as well
+	cases := []struct {
+		ts    time.Duration
+		index int64
+	}{
+		{1520 * time.Millisecond, 38},
+		{500 * time.Millisecond, 13},
+		{1530 * time.Millisecond, 39},
+		{0, 0},
+		{3960 * time.Millisecond, 99},
+	}
+
+	for _, c := range cases {
+		frame, err := inputCtx.SeekAccurate(ist.Index(), c.ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		index := RescaleQ(frame.BestEffortTimestamp()-ist.GetStartTime(), ist.TimeBase(), AVR{1, 25}.AVRational())
+		frame.Free()
+
+		if index != c.index {
+			t.Fatalf("Expected frame %d at %s, frame %d got\n", c.index, c.ts, index)
+		}
+	}
+
+	if _, err := inputCtx.SeekAccurate(ist.Index(), 10*time.Second); !isEOF(err) {
+		t.Fatalf("Expected ErrEOF seeking after the end, '%v' got\n", err)
+	}
+}
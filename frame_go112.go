@@ -26,6 +26,69 @@ void gmf_free_data(AVFrame *frame) {
 	av_freep(&frame->data[0]);
 }
 
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+#define GMF_CH_LAYOUT_API 1
+#endif
+
+static int64_t gmf_get_frame_pkt_pts(AVFrame *frame) {
+#if LIBAVUTIL_VERSION_MAJOR < 57
+	return frame->pkt_pts;
+#else
+	return frame->pts;
+#endif
+}
+
+static void gmf_set_frame_pkt_pts(AVFrame *frame, int64_t val) {
+#if LIBAVUTIL_VERSION_MAJOR < 57
+	frame->pkt_pts = val;
+#else
+	frame->pts = val;
+#endif
+}
+
+static int gmf_get_frame_channels(AVFrame *frame) {
+#ifdef GMF_CH_LAYOUT_API
+	return frame->ch_layout.nb_channels;
+#else
+	return frame->channels;
+#endif
+}
+
+static void gmf_set_frame_channels(AVFrame *frame, int channels) {
+#ifdef GMF_CH_LAYOUT_API
+	if (frame->ch_layout.nb_channels != channels) {
+		av_channel_layout_uninit(&frame->ch_layout);
+		frame->ch_layout.order       = AV_CHANNEL_ORDER_UNSPEC;
+		frame->ch_layout.nb_channels = channels;
+	}
+#else
+	frame->channels = channels;
+#endif
+}
+
+static uint64_t gmf_get_frame_channel_layout(AVFrame *frame) {
+#ifdef GMF_CH_LAYOUT_API
+	return frame->ch_layout.order == AV_CHANNEL_ORDER_NATIVE ? frame->ch_layout.u.mask : 0;
+#else
+	return frame->channel_layout;
+#endif
+}
+
+static void gmf_set_frame_channel_layout(AVFrame *frame, uint64_t layout) {
+#ifdef GMF_CH_LAYOUT_API
+	int channels = frame->ch_layout.nb_channels;
+
+	av_channel_layout_uninit(&frame->ch_layout);
+
+	if (!layout || av_channel_layout_from_mask(&frame->ch_layout, layout) < 0) {
+		frame->ch_layout.order       = AV_CHANNEL_ORDER_UNSPEC;
+		frame->ch_layout.nb_channels = channels;
+	}
+#else
+	frame->channel_layout = layout;
+#endif
+}
+
 */
 import "C"
 
@@ -105,12 +168,18 @@ func (f *Frame) SetPts(val int64) {
 	f.avFrame.pts = (C.int64_t)(val)
 }
 
+// BestEffortTimestamp returns frame timestamp estimated by the decoder, in stream time base.
+func (f *Frame) BestEffortTimestamp() int64 {
+	return int64(f.avFrame.best_effort_timestamp)
+}
+
+// PktPts returns pts copied from the packet. Since FFmpeg 5.0 it is the same as Pts.
 func (f *Frame) PktPts() int64 {
-	return int64(f.avFrame.pkt_pts)
+	return int64(C.gmf_get_frame_pkt_pts(f.avFrame))
 }
 
 func (f *Frame) SetPktPts(val int64) {
-	f.avFrame.pkt_pts = (C.int64_t)(val)
+	C.gmf_set_frame_pkt_pts(f.avFrame, (C.int64_t)(val))
 }
 
 func (f *Frame) PktDts() int {
@@ -202,6 +271,26 @@ func NewAudioFrame(sampleFormat int32, channels, nb_samples int) (*Frame, error)
 	return f, nil
 }
 
+// NewAudioFrameWithLayout is the same as NewAudioFrame, number of channels and
+// channel layout are taken from the layout.
+func NewAudioFrameWithLayout(sampleFormat int32, layout *ChannelLayout, nbSamples int) (*Frame, error) {
+	if layout == nil {
+		return nil, errors.New("channel layout is not initialized")
+	}
+
+	f, err := NewAudioFrame(sampleFormat, layout.NbChannels(), nbSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.SetChLayout(layout); err != nil {
+		f.Free()
+		return nil, err
+	}
+
+	return f, nil
+}
+
 func (f *Frame) SetData(idx int, lineSize int, data int) *Frame {
 	C.gmf_set_frame_data(f.avFrame, C.int(idx), C.int(lineSize), (C.uint8_t)(data))
 
@@ -239,20 +328,33 @@ func (f *Frame) SetNbSamples(val int) *Frame {
 }
 
 func (f *Frame) ChannelLayout() int {
-	return int(f.avFrame.channel_layout)
+	return int(C.gmf_get_frame_channel_layout(f.avFrame))
 }
 
 func (f *Frame) SetChannelLayout(val int) *Frame {
-	f.avFrame.channel_layout = (C.uint64_t)(val)
+	C.gmf_set_frame_channel_layout(f.avFrame, (C.uint64_t)(val))
 	return f
 }
 
+// ChLayout returns a copy of the frame channel layout, it must be freed.
+func (f *Frame) ChLayout() *ChannelLayout {
+	return channelLayoutFromFrame(f.avFrame)
+}
+
+func (f *Frame) SetChLayout(l *ChannelLayout) error {
+	if l == nil {
+		return errors.New("channel layout is not initialized")
+	}
+
+	return l.toFrame(f.avFrame)
+}
+
 func (f *Frame) Channels() int {
-	return int(f.avFrame.channels)
+	return int(C.gmf_get_frame_channels(f.avFrame))
 }
 
 func (f *Frame) SetChannels(val int) *Frame {
-	f.avFrame.channels = C.int(val)
+	C.gmf_set_frame_channels(f.avFrame, C.int(val))
 	return f
 }
 
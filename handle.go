@@ -0,0 +1,59 @@
+package gmf
+
+/*
+
+#include <stdlib.h>
+
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// Registry of Go values passed to C code as callback opaque, an equivalent of
+// runtime/cgo.Handle, which isn't available in go1.12.
+// Go pointers can't be kept by C code, so every value gets a unique C address,
+// which is used as a key. It's safe to use from multiple goroutines.
+var (
+	handles      = make(map[uintptr]interface{})
+	handlesMutex sync.RWMutex
+)
+
+// newHandle registers v and returns the opaque pointer, which should be passed to C.
+// deleteHandle must be called, when the opaque isn't used by C anymore.
+func newHandle(v interface{}) (unsafe.Pointer, error) {
+	opaque := C.malloc(1)
+	if opaque == nil {
+		return nil, errors.New("unable to allocate handle")
+	}
+
+	handlesMutex.Lock()
+	handles[uintptr(opaque)] = v
+	handlesMutex.Unlock()
+
+	return opaque, nil
+}
+
+// handleValue returns the value registered by newHandle, or nil if opaque is unknown.
+func handleValue(opaque unsafe.Pointer) interface{} {
+	handlesMutex.RLock()
+	defer handlesMutex.RUnlock()
+
+	return handles[uintptr(opaque)]
+}
+
+// deleteHandle unregisters the value and frees the opaque.
+func deleteHandle(opaque unsafe.Pointer) {
+	if opaque == nil {
+		return
+	}
+
+	handlesMutex.Lock()
+	delete(handles, uintptr(opaque))
+	handlesMutex.Unlock()
+
+	C.free(opaque)
+}
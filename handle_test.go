@@ -0,0 +1,181 @@
+package gmf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+// Number of contexts running in parallel, run with -race to detect data races.
+const concurrentContexts = 200
+
+func TestHandle(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrentContexts; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			opaque, err := newHandle(i)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if v := handleValue(opaque); v != i {
+				t.Errorf("Expected %d, %v got\n", i, v)
+			}
+
+			deleteHandle(opaque)
+
+			if v := handleValue(opaque); v != nil {
+				t.Errorf("Expected nil after delete, %v got\n", v)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// sliceReader returns ReadPacket handler reading data.
+func sliceReader(data []byte) func() ([]byte, int) {
+	r := bytes.NewReader(data)
+
+	return func() ([]byte, int) {
+		b := make([]byte, IO_BUFFER_SIZE)
+
+		n, err := r.Read(b)
+		if err == io.EOF {
+			return nil, AVERROR_EOF
+		}
+
+		return b, n
+	}
+}
+
+func readPackets(ctx *FmtCtx) (int, error) {
+	cnt := 0
+
+	it := ctx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		it.Packet().Free()
+		cnt++
+	}
+
+	return cnt, it.Err()
+}
+
+func readAVIOContext(data []byte) (int, error) {
+	ctx, err := NewCtx()
+	if err != nil {
+		return 0, err
+	}
+	defer ctx.Free()
+
+	avioCtx, err := NewAVIOContext(ctx, &AVIOHandlers{ReadPacket: sliceReader(data)})
+	if err != nil {
+		return 0, err
+	}
+	defer avioCtx.Free()
+
+	if err := ctx.SetPb(avioCtx).OpenInput(""); err != nil {
+		return 0, err
+	}
+
+	return readPackets(ctx)
+}
+
+func readReadSeeker(data []byte) (int, error) {
+	ctx, err := NewInputCtxFromReadSeeker(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	defer ctx.Free()
+
+	return readPackets(ctx)
+}
+
+func TestAVIOContextConcurrent(t *testing.T) {
+	data, err := ioutil.ReadFile(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 2*concurrentContexts)
+
+	for i := 0; i < concurrentContexts; i++ {
+		for _, read := range []func([]byte) (int, error){readAVIOContext, readReadSeeker} {
+			wg.Add(1)
+
+			go func(read func([]byte) (int, error)) {
+				defer wg.Done()
+
+				cnt, err := read(data)
+				if err == nil && cnt != 25 {
+					err = fmt.Errorf("Expected %d packets, obtained %d", 25, cnt)
+				}
+
+				errs <- err
+			}(read)
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAVIOContextReadWrite(t *testing.T) {
+	data, err := ioutil.ReadFile(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := NewCtx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Free()
+
+	reader := &AVIOHandlers{ReadPacket: sliceReader(data)}
+	writer := &AVIOHandlers{WritePacket: func(b []byte) int { return len(b) }}
+
+	rCtx, err := NewAVIOContext(ctx, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rCtx.Free()
+
+	// the writer must not replace the reader handlers of the same format context
+	wCtx, err := NewAVIOContext(ctx, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wCtx.Free()
+
+	if handleValue(rCtx.opaque) != reader || handleValue(wCtx.opaque) != writer {
+		t.Fatalf("Expected separate handlers for read and write contexts\n")
+	}
+
+	if err := ctx.SetPb(rCtx).OpenInput(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if cnt := countPackets(t, ctx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
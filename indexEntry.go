@@ -0,0 +1,206 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat
+
+#include "libavformat/avformat.h"
+
+typedef struct {
+	int64_t pos;
+	int64_t timestamp;
+	int     flags;
+	int     size;
+	int     min_distance;
+} gmf_index_entry;
+
+// index_entries are private since FFmpeg 5.0, accessors are available since 4.4.1
+static int gmf_index_entries_count(AVStream *st) {
+#if LIBAVFORMAT_VERSION_INT >= AV_VERSION_INT(58, 78, 100)
+	return avformat_index_get_entries_count(st);
+#else
+	return st->nb_index_entries;
+#endif
+}
+
+static int gmf_index_entry_get(AVStream *st, int idx, gmf_index_entry *dst) {
+	const AVIndexEntry *e;
+
+#if LIBAVFORMAT_VERSION_INT >= AV_VERSION_INT(58, 78, 100)
+	e = avformat_index_get_entry(st, idx);
+#else
+	e = idx >= 0 && idx < st->nb_index_entries ? &st->index_entries[idx] : NULL;
+#endif
+	if (!e) {
+		return -1;
+	}
+
+	dst->pos          = e->pos;
+	dst->timestamp    = e->timestamp;
+	dst->flags        = e->flags;
+	dst->size         = e->size;
+	dst->min_distance = e->min_distance;
+
+	return 0;
+}
+
+*/
+import "C"
+
+const (
+	AVINDEX_KEYFRAME      int = C.AVINDEX_KEYFRAME
+	AVINDEX_DISCARD_FRAME int = C.AVINDEX_DISCARD_FRAME
+)
+
+// IndexEntry is a copy of AVIndexEntry. Timestamp is in the stream time base.
+type IndexEntry struct {
+	Pos         int64
+	Timestamp   int64
+	Flags       int
+	Size        int
+	MinDistance int
+}
+
+func (e IndexEntry) IsKeyframe() bool {
+	return e.Flags&AVINDEX_KEYFRAME != 0
+}
+
+// IndexEntries returns entries of the demuxer index, e.g. samples of mp4
+// or cues of mkv. Formats without an index (e.g. mpegts) may have no entries,
+// or entries of the already read part only.
+func (s *Stream) IndexEntries() []IndexEntry {
+	cnt := int(C.gmf_index_entries_count(s.avStream))
+	result := make([]IndexEntry, 0, cnt)
+
+	var entry C.gmf_index_entry
+
+	for i := 0; i < cnt; i++ {
+		if C.gmf_index_entry_get(s.avStream, C.int(i), &entry) < 0 {
+			break
+		}
+
+		result = append(result, IndexEntry{
+			Pos:         int64(entry.pos),
+			Timestamp:   int64(entry.timestamp),
+			Flags:       int(entry.flags),
+			Size:        int(entry.size),
+			MinDistance: int(entry.min_distance),
+		})
+	}
+
+	return result
+}
+
+// KeyframeEntries returns keyframe entries of the stream index. If the demuxer
+// has no index, keyframes are scanned by ScanKeyframes. Some demuxers (e.g. mpegts)
+// only index packets, which are already read, such index doesn't cover the whole
+// duration and it's returned as is, use ScanKeyframes to get all the keyframes.
+func (this *FmtCtx) KeyframeEntries(streamIndex int) ([]IndexEntry, error) {
+	st, err := this.GetStream(streamIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IndexEntry, 0)
+
+	for _, e := range st.IndexEntries() {
+		if e.IsKeyframe() {
+			result = append(result, e)
+		}
+	}
+
+	if len(result) > 0 {
+		return result, nil
+	}
+
+	return this.scanKeyframes(st)
+}
+
+// ScanKeyframes reads packets of the stream from the current position up to
+// the end, keyframes are detected by AV_PKT_FLAG_KEY, Timestamp is the packet dts
+// (pts, if dts isn't set). The input is seeked back after the scan, to the keyframe
+// at or before the first scanned packet, so reading continues from the same
+// position, if it was a keyframe, e.g. the start of the input. Not seekable input
+// isn't seeked back, it's consumed by the scan. If the seek back fails, the scanned
+// entries are returned along with the error.
+func (this *FmtCtx) ScanKeyframes(streamIndex int) ([]IndexEntry, error) {
+	st, err := this.GetStream(streamIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return this.scanKeyframes(st)
+}
+
+func (this *FmtCtx) scanKeyframes(st *Stream) ([]IndexEntry, error) {
+	var (
+		result  []IndexEntry = make([]IndexEntry, 0)
+		startTs int64        = AV_NOPTS_VALUE
+	)
+
+	// other streams aren't needed for the scan
+	discard := make(map[int]Discard)
+
+	for i := 0; i < this.StreamsCnt(); i++ {
+		if other, err := this.GetStream(i); err == nil && i != st.Index() {
+			discard[i] = other.Discard()
+			other.SetDiscard(AVDISCARD_ALL)
+		}
+	}
+
+	defer func() {
+		for i, level := range discard {
+			if other, err := this.GetStream(i); err == nil {
+				other.SetDiscard(level)
+			}
+		}
+	}()
+
+	for {
+		pkt, err := this.GetNextPacket()
+		if isEOF(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ts := pkt.Dts()
+		if ts == AV_NOPTS_VALUE {
+			ts = pkt.Pts()
+		}
+
+		if pkt.StreamIndex() == st.Index() {
+			if startTs == AV_NOPTS_VALUE {
+				startTs = ts
+			}
+
+			if pkt.Flags()&AV_PKT_FLAG_KEY != 0 {
+				result = append(result, IndexEntry{
+					Pos:       pkt.Pos(),
+					Timestamp: ts,
+					Flags:     AVINDEX_KEYFRAME,
+					Size:      pkt.Size(),
+				})
+			}
+		}
+
+		pkt.Free()
+	}
+
+	// nothing is read, the input is at the end already
+	if startTs == AV_NOPTS_VALUE {
+		return result, nil
+	}
+
+	// not seekable input (e.g. a pipe) can't be seeked back, it's consumed by the scan
+	if this.avCtx.pb != nil && this.avCtx.pb.seekable == 0 {
+		return result, nil
+	}
+
+	if averr := C.avformat_seek_file(this.avCtx, C.int(st.Index()), C.INT64_MIN, C.int64_t(startTs), C.int64_t(startTs), 0); averr < 0 {
+		return result, newAVError(int(averr), "Unable to seek back after keyframes scan of", this.filename)
+	}
+
+	return result, nil
+}
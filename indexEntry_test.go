@@ -0,0 +1,152 @@
+package gmf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndexEntries(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	entries := ist.IndexEntries()
+	if len(entries) != 25 {
+		t.Fatalf("Expected 25 index entries, %d got\n", len(entries))
+	}
+
+	if !entries[0].IsKeyframe() || entries[0].Pos <= 0 || entries[0].Size <= 0 {
+		t.Fatalf("Expected first entry to be a keyframe, %+v got\n", entries[0])
+	}
+
+	keyframes, err := inputCtx.KeyframeEntries(ist.Index())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keyframes) == 0 || len(keyframes) > len(entries) {
+		t.Fatalf("Unexpected %d keyframes of %d entries\n", len(keyframes), len(entries))
+	}
+
+	// mpegts has no index, so packets are scanned
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	tsCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tsCtx.Free()
+
+	scanned, err := tsCtx.KeyframeEntries(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scanned) != len(keyframes) {
+		t.Fatalf("Expected %d scanned keyframes, %d got\n", len(keyframes), len(scanned))
+	}
+
+	// the input is seeked back to the start
+	if cnt := countPackets(t, tsCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets after scan, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestScanKeyframesPosition(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	entries := assert(inputCtx.GetStream(0)).(*Stream).IndexEntries()
+
+	for i := 0; i < 5; i++ {
+		pkt, err := inputCtx.GetNextPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkt.Free()
+	}
+
+	scanned, err := inputCtx.ScanKeyframes(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range scanned {
+		if e.Timestamp < entries[5].Timestamp {
+			t.Fatalf("Expected keyframes after the position only, %+v got\n", e)
+		}
+	}
+
+	// reading continues from the keyframe at or before the position, not the start
+	pkt, err := inputCtx.GetNextPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkt.Free()
+
+	if pkt.Flags()&AV_PKT_FLAG_KEY == 0 || pkt.Dts() > entries[5].Timestamp {
+		t.Fatalf("Expected keyframe at or before %d, dts %d got\n", entries[5].Timestamp, pkt.Dts())
+	}
+
+	var last int64 = AV_NOPTS_VALUE
+	for _, e := range entries[:6] {
+		if e.IsKeyframe() {
+			last = e.Timestamp
+		}
+	}
+
+	if pkt.Dts() != last {
+		t.Fatalf("Expected the last keyframe %d before the position, %d got\n", last, pkt.Dts())
+	}
+}
+
+func TestScanKeyframesNotSeekable(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtxFromReader(&onlyReader{r: bytes.NewReader(buf.Bytes())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	scanned, err := inputCtx.ScanKeyframes(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scanned) == 0 {
+		t.Fatalf("Expected scanned keyframes of not seekable input\n")
+	}
+
+	// the input is consumed by the scan
+	if cnt := countPackets(t, inputCtx); cnt != 0 {
+		t.Fatalf("Expected no packets after scan, obtained %d\n", cnt)
+	}
+}
@@ -0,0 +1,64 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat
+
+#include "libavformat/avformat.h"
+
+extern int interruptCallBack(void*);
+
+*/
+import "C"
+
+import (
+	"context"
+	"sync"
+	"unsafe"
+)
+
+// Global map of contexts, which are currently bound to a format context.
+// Using ctx.avCtx pointer address as a key, it is passed to FFmpeg as
+// the interrupt callback opaque.
+var (
+	interruptMap   = make(map[uintptr]context.Context)
+	interruptMutex sync.RWMutex
+)
+
+// bindInterrupt connects ctx cancellation to the AVFormatContext
+// interrupt_callback, so blocking calls like avformat_open_input or
+// av_read_frame return AVERROR_EXIT once ctx is done.
+// Returned function must be called to unbind ctx.
+func (this *FmtCtx) bindInterrupt(ctx context.Context) func() {
+	if this.avCtx == nil || ctx == nil || ctx.Done() == nil {
+		return func() {}
+	}
+
+	key := uintptr(unsafe.Pointer(this.avCtx))
+
+	interruptMutex.Lock()
+	interruptMap[key] = ctx
+	interruptMutex.Unlock()
+
+	this.avCtx.interrupt_callback.callback = (*[0]byte)(C.interruptCallBack)
+	this.avCtx.interrupt_callback.opaque = unsafe.Pointer(this.avCtx)
+
+	return func() {
+		interruptMutex.Lock()
+		delete(interruptMap, key)
+		interruptMutex.Unlock()
+	}
+}
+
+//export interruptCallBack
+func interruptCallBack(opaque unsafe.Pointer) C.int {
+	interruptMutex.RLock()
+	ctx, found := interruptMap[uintptr(opaque)]
+	interruptMutex.RUnlock()
+
+	if found && ctx.Err() != nil {
+		return 1
+	}
+
+	return 0
+}
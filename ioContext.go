@@ -0,0 +1,334 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavutil
+
+#include <stdlib.h>
+
+#include "libavformat/avformat.h"
+#include "libavformat/avio.h"
+#include "libavutil/mem.h"
+#include "libavutil/opt.h"
+
+extern int gmfIOReadCallBack(void*, uint8_t*, int);
+extern int gmfIOWriteCallBack(void*, uint8_t*, int);
+extern int64_t gmfIOSeekCallBack(void*, int64_t, int);
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// ioHandler is Go side of the AVIOContext created for io.Reader or io.Writer.
+type ioHandler struct {
+	r   io.Reader
+	w   io.Writer
+	s   io.Seeker
+	err error
+}
+
+// ioContext is AVIOContext, which is owned by FmtCtx.
+type ioContext struct {
+	avioCtx *C.AVIOContext
+	opaque  unsafe.Pointer
+	handler *ioHandler
+}
+
+func newIOContext(h *ioHandler, bufferSize int) (*ioContext, error) {
+	buffer := C.av_malloc(C.size_t(bufferSize))
+	if buffer == nil {
+		return nil, errors.New("unable to allocate buffer")
+	}
+
+	opaque, err := newHandle(h)
+	if err != nil {
+		C.av_free(buffer)
+		return nil, err
+	}
+
+	var (
+		ptrRead, ptrWrite, ptrSeek *[0]byte = nil, nil, nil
+		flag                       int      = 0
+	)
+
+	if h.r != nil {
+		ptrRead = (*[0]byte)(C.gmfIOReadCallBack)
+	}
+
+	if h.w != nil {
+		ptrWrite = (*[0]byte)(C.gmfIOWriteCallBack)
+		flag = 1
+	}
+
+	if h.s != nil {
+		ptrSeek = (*[0]byte)(C.gmfIOSeekCallBack)
+	}
+
+	avioCtx := C.avio_alloc_context((*C.uchar)(buffer), C.int(bufferSize), C.int(flag), opaque, ptrRead, ptrWrite, ptrSeek)
+	if avioCtx == nil {
+		C.av_free(buffer)
+		deleteHandle(opaque)
+		return nil, errors.New("unable to initialize avio context")
+	}
+
+	return &ioContext{avioCtx: avioCtx, opaque: opaque, handler: h}, nil
+}
+
+func (this *ioContext) Free() {
+	if this.avioCtx != nil {
+		// buffer may be reallocated by libavformat, so it's freed by the context pointer
+		C.av_freep(unsafe.Pointer(&this.avioCtx.buffer))
+		C.avio_context_free(&this.avioCtx)
+	}
+
+	deleteHandle(this.opaque)
+}
+
+func lookupIOHandler(opaque unsafe.Pointer) *ioHandler {
+	h, _ := handleValue(opaque).(*ioHandler)
+	return h
+}
+
+// fail keeps Go error to be returned instead of AVERROR_EXTERNAL
+// by the FmtCtx methods. io.EOF is mapped to AVERROR_EOF.
+func (h *ioHandler) fail(err error) int {
+	if err == io.EOF {
+		return AVERROR_EOF
+	}
+
+	h.err = err
+
+	return AVERROR_EXTERNAL
+}
+
+//export gmfIOReadCallBack
+func gmfIOReadCallBack(opaque unsafe.Pointer, buf *C.uint8_t, bufSize C.int) C.int {
+	h := lookupIOHandler(opaque)
+	if h == nil || h.r == nil {
+		return C.int(AVERROR_EXTERNAL)
+	}
+
+	if bufSize <= 0 {
+		return 0
+	}
+
+	// read straight into the libavformat buffer
+	dst := (*[1 << 30]byte)(unsafe.Pointer(buf))[:int(bufSize):int(bufSize)]
+
+	n, err := io.ReadAtLeast(h.r, dst, 1)
+	if n > 0 {
+		// error, if any, will be returned by the next Read
+		return C.int(n)
+	}
+
+	return C.int(h.fail(err))
+}
+
+//export gmfIOWriteCallBack
+func gmfIOWriteCallBack(opaque unsafe.Pointer, buf *C.uint8_t, bufSize C.int) C.int {
+	h := lookupIOHandler(opaque)
+	if h == nil || h.w == nil {
+		return C.int(AVERROR_EXTERNAL)
+	}
+
+	if bufSize <= 0 {
+		return 0
+	}
+
+	src := (*[1 << 30]byte)(unsafe.Pointer(buf))[:int(bufSize):int(bufSize)]
+
+	n, err := h.w.Write(src)
+	if err == nil && n < len(src) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		// io.EOF from the writer is an error as well
+		h.err = err
+		return C.int(AVERROR_EXTERNAL)
+	}
+
+	return C.int(n)
+}
+
+//export gmfIOSeekCallBack
+func gmfIOSeekCallBack(opaque unsafe.Pointer, offset C.int64_t, whence C.int) C.int64_t {
+	h := lookupIOHandler(opaque)
+	if h == nil || h.s == nil {
+		return C.int64_t(-int(syscall.ENOSYS))
+	}
+
+	if whence&C.AVSEEK_SIZE != 0 {
+		cur, err := h.s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return C.int64_t(-int(syscall.ENOSYS))
+		}
+
+		size, err := h.s.Seek(0, io.SeekEnd)
+		if _, err2 := h.s.Seek(cur, io.SeekStart); err != nil || err2 != nil {
+			return C.int64_t(-int(syscall.ENOSYS))
+		}
+
+		return C.int64_t(size)
+	}
+
+	pos, err := h.s.Seek(int64(offset), int(whence&^C.AVSEEK_FORCE))
+	if err != nil {
+		return C.int64_t(h.fail(err))
+	}
+
+	return C.int64_t(pos)
+}
+
+// NewInputCtxFromReader opens input read from r. Data is copied directly into
+// libavformat buffer. If r implements io.Seeker and seeking works (e.g. *os.File,
+// but not a pipe), the input is seekable, otherwise only formats, which don't
+// need seeking, can be demuxed (e.g. mpegts or fragmented mp4).
+// Errors returned by r, except io.EOF, are returned as is by OpenInput and reading methods.
+// AVIOContext is owned by the FmtCtx and freed by Free.
+func NewInputCtxFromReader(r io.Reader, options ...*Option) (*FmtCtx, error) {
+	if r == nil {
+		return nil, errors.New("reader is not initialized")
+	}
+
+	h := &ioHandler{r: r}
+
+	if s, ok := r.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekCurrent); err == nil {
+			h.s = s
+		}
+	}
+
+	return newInputCtxFromIO(h, options...)
+}
+
+// NewInputCtxFromReadSeeker is the same as NewInputCtxFromReader, the input is always seekable.
+func NewInputCtxFromReadSeeker(rs io.ReadSeeker, options ...*Option) (*FmtCtx, error) {
+	if rs == nil {
+		return nil, errors.New("reader is not initialized")
+	}
+
+	return newInputCtxFromIO(&ioHandler{r: rs, s: rs}, options...)
+}
+
+func newInputCtxFromIO(h *ioHandler, options ...*Option) (*FmtCtx, error) {
+	ctx, err := NewCtx(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.ioCtx, err = newIOContext(h, IO_BUFFER_SIZE); err != nil {
+		ctx.Free()
+		return nil, err
+	}
+
+	ctx.avCtx.pb = ctx.ioCtx.avioCtx
+	ctx.customPb = true
+
+	var iDict *Dict = nil
+
+	for _, option := range options {
+		if strings.Compare(option.Key, "input_options") == 0 {
+			iDict = option.Val.(*Dict)
+		}
+	}
+
+	if err := ctx.OpenInputWithOption("", iDict); err != nil {
+		ctx.Free()
+		return nil, err
+	}
+
+	ctx.isInput = true
+
+	return ctx, nil
+}
+
+// NewOutputCtxToWriter creates output context of the format (e.g. "mp4", "mpegts"),
+// which is written to w. If w implements io.Seeker and seeking works, the output
+// is seekable. Otherwise streaming friendly muxer options are set, e.g. fragmented
+// mp4 ("movflags=+frag_keyframe+empty_moov+default_base_moof"), options passed
+// by caller are applied after them.
+// Errors returned by w are returned as is by WriteHeader, WritePacket and WriteTrailer.
+// AVIOContext is owned by the FmtCtx and freed by Free.
+func NewOutputCtxToWriter(w io.Writer, format string, options ...*Option) (*FmtCtx, error) {
+	if w == nil {
+		return nil, errors.New("writer is not initialized")
+	}
+
+	h := &ioHandler{w: w}
+
+	if s, ok := w.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekCurrent); err == nil {
+			h.s = s
+		}
+	}
+
+	cformat := C.CString(format)
+	defer C.free(unsafe.Pointer(cformat))
+
+	ofmt := C.av_guess_format(cformat, nil, nil)
+	if ofmt == nil {
+		return nil, newAVError(AVERROR_MUXER_NOT_FOUND, fmt.Sprintf("Unable to find output format '%s'", format), "")
+	}
+
+	ctx := &FmtCtx{streams: make(map[int]*Stream), isInput: false}
+
+	if averr := C.avformat_alloc_output_context2(&ctx.avCtx, ofmt, nil, nil); averr < 0 {
+		return nil, newAVError(int(averr), "Error creating output context", "")
+	}
+
+	ctx.ofmt = &OutputFmt{avOutputFmt: ctx.avCtx.oformat}
+
+	var err error
+	if ctx.ioCtx, err = newIOContext(h, IO_BUFFER_SIZE); err != nil {
+		ctx.Free()
+		return nil, err
+	}
+
+	ctx.avCtx.pb = ctx.ioCtx.avioCtx
+	ctx.customPb = true
+
+	if h.s == nil {
+		for _, option := range streamingOutputOptions {
+			if ctx.hasOption(option.Key) {
+				option.Set(ctx.avCtx)
+			}
+		}
+	}
+
+	ctx.SetOptions(options)
+
+	return ctx, nil
+}
+
+// hasOption checks, if the context or its (de)muxer private data has the option.
+func (this *FmtCtx) hasOption(name string) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	return C.av_opt_find(unsafe.Pointer(this.avCtx), cname, nil, 0, C.AV_OPT_SEARCH_CHILDREN) != nil
+}
+
+// Muxer options for non-seekable output.
+var streamingOutputOptions = []*Option{
+	{"movflags", "+frag_keyframe+empty_moov+default_base_moof"},
+}
+
+// ioError returns error of the Go reader or writer, if the operation is failed because of it
+// (AVERROR_EXTERNAL). The kept error is reset, so it's returned once only.
+func (this *FmtCtx) ioError(averr int, op, url string) error {
+	if averr == AVERROR_EXTERNAL && this.ioCtx != nil && this.ioCtx.handler.err != nil {
+		err := this.ioCtx.handler.err
+		this.ioCtx.handler.err = nil
+		return err
+	}
+
+	return newAVError(averr, op, url)
+}
@@ -0,0 +1,285 @@
+package gmf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// onlyReader hides io.Seeker of the underlying reader.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (r *onlyReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func countPackets(t *testing.T, ctx *FmtCtx) int {
+	cnt := 0
+
+	it := ctx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		it.Packet().Free()
+		cnt++
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return cnt
+}
+
+func TestInputCtxFromReadSeeker(t *testing.T) {
+	data, err := ioutil.ReadFile(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := NewInputCtxFromReadSeeker(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Free()
+
+	if cnt := countPackets(t, ctx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+
+	ist := assert(ctx.GetStream(0)).(*Stream)
+	if err := ctx.SeekFile(ist, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if cnt := countPackets(t, ctx); cnt != 25 {
+		t.Fatalf("Expected %d packets after seek, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestInputCtxFromReader(t *testing.T) {
+	f, err := os.Open(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// *os.File is seekable, so seeking is enabled automatically
+	ctx, err := NewInputCtxFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Free()
+
+	if ctx.ioCtx.handler.s == nil {
+		t.Fatalf("Expected seekable input\n")
+	}
+
+	if cnt := countPackets(t, ctx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestInputCtxFromReaderError(t *testing.T) {
+	data, err := ioutil.ReadFile(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readErr := errors.New("connection reset")
+
+	r := io.MultiReader(bytes.NewReader(data[:64]), &errorReader{err: readErr})
+
+	if _, err := NewInputCtxFromReader(&onlyReader{r: r}); err != readErr {
+		t.Fatalf("Expected reader error, '%v' got\n", err)
+	}
+
+	// empty input, io.EOF is not an error of the reader
+	if _, err := NewInputCtxFromReader(&onlyReader{r: bytes.NewReader(nil)}); err == nil || err == io.EOF {
+		t.Fatalf("Expected error opening empty input, '%v' got\n", err)
+	}
+}
+
+func TestInputCtxIOErrorReset(t *testing.T) {
+	data, err := ioutil.ReadFile(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := NewInputCtxFromReader(&onlyReader{r: bytes.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Free()
+
+	readErr := errors.New("connection reset")
+	ctx.ioCtx.handler.err = readErr
+
+	// errors, which aren't caused by the reader, are kept as is
+	if err := ctx.ioError(AVERROR_INVALIDDATA, "read", ""); !isAVError(err, AVERROR_INVALIDDATA) {
+		t.Fatalf("Expected AVERROR_INVALIDDATA, '%v' got\n", err)
+	}
+
+	if err := ctx.ioError(AVERROR_EXTERNAL, "read", ""); err != readErr {
+		t.Fatalf("Expected reader error, '%v' got\n", err)
+	}
+
+	// the reader error is returned once
+	if err := ctx.ioError(AVERROR_EXTERNAL, "read", ""); !isAVError(err, AVERROR_EXTERNAL) {
+		t.Fatalf("Expected AVERROR_EXTERNAL, '%v' got\n", err)
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// onlyWriter hides io.Seeker of the underlying writer.
+type onlyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *onlyWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	return w.w.Write(p)
+}
+
+func remuxSample(t *testing.T, outputCtx *FmtCtx) error {
+	return remuxSampleWith(t, outputCtx, nil)
+}
+
+// remuxSampleWith calls prepare, if any, before the output header is written.
+func remuxSampleWith(t *testing.T, outputCtx *FmtCtx, prepare func(inputCtx *FmtCtx, ist, ost *Stream)) error {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	ost := outputCtx.NewStream(nil)
+	if ost == nil {
+		t.Fatal("unable to create stream")
+	}
+
+	if err := ost.CopyCodecPar(ist.GetCodecPar()); err != nil {
+		t.Fatal(err)
+	}
+	ost.avStream.codecpar.codec_tag = 0
+	ost.SetTimeBase(ist.TimeBase().AVR())
+
+	if prepare != nil {
+		prepare(inputCtx, ist, ost)
+	}
+
+	if err := outputCtx.WriteHeader(); err != nil {
+		return err
+	}
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		pkt := it.Packet()
+		RescaleTs(pkt, ist.TimeBase(), ost.TimeBase())
+		pkt.SetStreamIndex(ost.Index())
+
+		err := outputCtx.WritePacket(pkt)
+		pkt.Free()
+		if err != nil {
+			return err
+		}
+	}
+
+	return outputCtx.WriteTrailer()
+}
+
+func TestOutputCtxToWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(&onlyWriter{w: buf}, "mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	// not seekable output is fragmented mp4
+	if !bytes.Contains(buf.Bytes(), []byte("moof")) {
+		t.Fatalf("Expected fragmented mp4\n")
+	}
+
+	inputCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	if cnt := countPackets(t, inputCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestOutputCtxToWriteSeeker(t *testing.T) {
+	f, err := ioutil.TempFile("", "gmf-writer-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	outputCtx, err := NewOutputCtxToWriter(f, "mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remuxSample(t, outputCtx); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	if cnt := countPackets(t, inputCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestOutputCtxToWriterError(t *testing.T) {
+	writeErr := errors.New("broken pipe")
+
+	outputCtx, err := NewOutputCtxToWriter(&onlyWriter{w: ioutil.Discard, err: writeErr}, "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	if err := remuxSample(t, outputCtx); err != writeErr {
+		t.Fatalf("Expected writer error, '%v' got\n", err)
+	}
+
+	if _, err := NewOutputCtxToWriter(ioutil.Discard, "not_existing_format"); !isAVError(err, AVERROR_MUXER_NOT_FOUND) {
+		t.Fatalf("Expected ErrMuxerNotFound, '%v' got\n", err)
+	}
+}
@@ -2,20 +2,119 @@ package gmf
 
 /*
 
-#cgo pkg-config: libavutil
+#cgo pkg-config: libavutil libavfilter
 
 #include <stdlib.h>
 #include "libavutil/opt.h"
+#include "libavutil/mem.h"
+#include "libavfilter/avfilter.h"
+
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+#define GMF_OPT_TYPE_CHLAYOUT AV_OPT_TYPE_CHLAYOUT
+#else
+#define GMF_OPT_TYPE_CHLAYOUT AV_OPT_TYPE_CHANNEL_LAYOUT
+#endif
+
+// default_val is a union, types with string default are listed in AVOption docs
+static int gmf_opt_has_str_default(const AVOption *o) {
+	switch ((int)o->type) {
+	case AV_OPT_TYPE_STRING:
+	case AV_OPT_TYPE_BINARY:
+	case AV_OPT_TYPE_DICT:
+	case AV_OPT_TYPE_IMAGE_SIZE:
+	case AV_OPT_TYPE_VIDEO_RATE:
+	case AV_OPT_TYPE_COLOR:
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+	case AV_OPT_TYPE_CHLAYOUT:
+#endif
+		return 1;
+	}
+
+	return 0;
+}
+
+static int64_t gmf_opt_default_i64(const AVOption *o) {
+	return o->default_val.i64;
+}
+
+static double gmf_opt_default_dbl(const AVOption *o) {
+	return o->default_val.dbl;
+}
+
+static const char *gmf_opt_default_str(const AVOption *o) {
+	return gmf_opt_has_str_default(o) ? o->default_val.str : NULL;
+}
+
+static const char *gmf_opt_class_name(void *obj) {
+	const AVClass *cls = *(const AVClass **)obj;
+
+	return cls ? cls->class_name : "";
+}
+
+static const AVOption *gmf_opt_find(void *obj, const char *name, void **target) {
+	return av_opt_find2(obj, name, NULL, 0, AV_OPT_SEARCH_CHILDREN, target);
+}
+
+static AVFilterContext *gmf_opt_graph_filter(AVFilterGraph *graph, int idx) {
+	return idx < (int)graph->nb_filters ? graph->filters[idx] : NULL;
+}
+
+static const AVClass *gmf_opt_filter_class(const char *name) {
+	const AVFilter *filter = avfilter_get_by_name(name);
+
+	return filter ? filter->priv_class : NULL;
+}
 
 */
 import "C"
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"reflect"
+	"strings"
+	"syscall"
+	"time"
 	"unsafe"
 )
 
+// Option types, see enum AVOptionType. AV_OPT_TYPE_CHLAYOUT is the
+// legacy AV_OPT_TYPE_CHANNEL_LAYOUT before FFmpeg 5.1.
+const (
+	AV_OPT_TYPE_FLAGS      int = C.AV_OPT_TYPE_FLAGS
+	AV_OPT_TYPE_INT        int = C.AV_OPT_TYPE_INT
+	AV_OPT_TYPE_INT64      int = C.AV_OPT_TYPE_INT64
+	AV_OPT_TYPE_DOUBLE     int = C.AV_OPT_TYPE_DOUBLE
+	AV_OPT_TYPE_FLOAT      int = C.AV_OPT_TYPE_FLOAT
+	AV_OPT_TYPE_STRING     int = C.AV_OPT_TYPE_STRING
+	AV_OPT_TYPE_RATIONAL   int = C.AV_OPT_TYPE_RATIONAL
+	AV_OPT_TYPE_BINARY     int = C.AV_OPT_TYPE_BINARY
+	AV_OPT_TYPE_DICT       int = C.AV_OPT_TYPE_DICT
+	AV_OPT_TYPE_UINT64     int = C.AV_OPT_TYPE_UINT64
+	AV_OPT_TYPE_CONST      int = C.AV_OPT_TYPE_CONST
+	AV_OPT_TYPE_IMAGE_SIZE int = C.AV_OPT_TYPE_IMAGE_SIZE
+	AV_OPT_TYPE_PIXEL_FMT  int = C.AV_OPT_TYPE_PIXEL_FMT
+	AV_OPT_TYPE_SAMPLE_FMT int = C.AV_OPT_TYPE_SAMPLE_FMT
+	AV_OPT_TYPE_VIDEO_RATE int = C.AV_OPT_TYPE_VIDEO_RATE
+	AV_OPT_TYPE_DURATION   int = C.AV_OPT_TYPE_DURATION
+	AV_OPT_TYPE_COLOR      int = C.AV_OPT_TYPE_COLOR
+	AV_OPT_TYPE_BOOL       int = C.AV_OPT_TYPE_BOOL
+	AV_OPT_TYPE_CHLAYOUT   int = C.GMF_OPT_TYPE_CHLAYOUT
+)
+
+const (
+	AV_OPT_FLAG_ENCODING_PARAM  int = C.AV_OPT_FLAG_ENCODING_PARAM
+	AV_OPT_FLAG_DECODING_PARAM  int = C.AV_OPT_FLAG_DECODING_PARAM
+	AV_OPT_FLAG_AUDIO_PARAM     int = C.AV_OPT_FLAG_AUDIO_PARAM
+	AV_OPT_FLAG_VIDEO_PARAM     int = C.AV_OPT_FLAG_VIDEO_PARAM
+	AV_OPT_FLAG_SUBTITLE_PARAM  int = C.AV_OPT_FLAG_SUBTITLE_PARAM
+	AV_OPT_FLAG_EXPORT          int = C.AV_OPT_FLAG_EXPORT
+	AV_OPT_FLAG_READONLY        int = C.AV_OPT_FLAG_READONLY
+	AV_OPT_FLAG_FILTERING_PARAM int = C.AV_OPT_FLAG_FILTERING_PARAM
+	AV_OPT_FLAG_DEPRECATED      int = C.AV_OPT_FLAG_DEPRECATED
+)
+
 type Option struct {
 	Key string
 	Val interface{}
@@ -54,6 +153,9 @@ func (this Option) Set(ctx interface{}) error {
 		defer C.free(unsafe.Pointer(cval))
 		ret = int(C.av_opt_set(unsafe.Pointer(reflect.ValueOf(ctx).Pointer()), ckey, cval, C.AV_OPT_SEARCH_CHILDREN))
 
+	case *ChannelLayout:
+		ret = this.Val.(*ChannelLayout).setOption(unsafe.Pointer(reflect.ValueOf(ctx).Pointer()), ckey)
+
 	case *Dict:
 		ret = int(C.av_opt_set_dict(unsafe.Pointer(reflect.ValueOf(ctx).Pointer()), &this.Val.(*Dict).dict))
 
@@ -68,3 +170,405 @@ func (this Option) Set(ctx interface{}) error {
 
 	return nil
 }
+
+// OptionConstant is a named value of the option, e.g. "medium" of libx264 "preset".
+type OptionConstant struct {
+	Name  string
+	Help  string
+	Value int64
+}
+
+// OptionInfo describes AVOption of the object.
+//
+// Default and Value are typed by Type: bool for AV_OPT_TYPE_BOOL (nil for "auto"),
+// int64 for integers and flags, int32 for pixel and sample formats, time.Duration
+// for durations, float64 for floats, AVR for rationals, string for the rest.
+type OptionInfo struct {
+	Name string
+	Help string
+	// Class is the name of the object class the option belongs to,
+	// e.g. "AVCodecContext" or "libx264" for the codec private options.
+	Class     string
+	Type      int
+	Unit      string
+	Flags     int
+	Min       float64
+	Max       float64
+	Default   interface{}
+	Constants []OptionConstant
+	// Value is the current value, nil for FilterOptions.
+	Value interface{}
+}
+
+func (o *OptionInfo) IsReadOnly() bool {
+	return o.Flags&AV_OPT_FLAG_READONLY != 0
+}
+
+// optionsObj returns AVClass enabled struct of the gmf object.
+func optionsObj(obj interface{}) (unsafe.Pointer, error) {
+	var ptr unsafe.Pointer
+
+	switch o := obj.(type) {
+	case *FmtCtx:
+		ptr = unsafe.Pointer(o.avCtx)
+	case *CodecCtx:
+		ptr = unsafe.Pointer(o.avCodecCtx)
+	case *SwsCtx:
+		ptr = unsafe.Pointer(o.swsCtx)
+	case *SwrCtx:
+		ptr = unsafe.Pointer(o.swrCtx)
+	case *BitstreamFilter:
+		ptr = unsafe.Pointer(o.avBSFCtx)
+	case *FilterGraph:
+		ptr = unsafe.Pointer(o.filterGraph)
+	case *Filter:
+		ptr = unsafe.Pointer(o.filterGraph)
+	default:
+		return nil, errors.New(fmt.Sprintf("Options of '%T' are not supported", obj))
+	}
+
+	if ptr == nil {
+		return nil, errors.New(fmt.Sprintf("Options of '%T' are unavailable, it isn't initialized", obj))
+	}
+
+	return ptr, nil
+}
+
+// graphFilters returns filter contexts, if obj is a filter graph. Options of the
+// filters aren't children of the graph, so they are handled separately.
+func graphFilters(obj interface{}, ptr unsafe.Pointer) []unsafe.Pointer {
+	switch obj.(type) {
+	case *FilterGraph, *Filter:
+	default:
+		return nil
+	}
+
+	result := make([]unsafe.Pointer, 0)
+
+	for i := 0; ; i++ {
+		f := C.gmf_opt_graph_filter((*C.AVFilterGraph)(ptr), C.int(i))
+		if f == nil {
+			break
+		}
+
+		result = append(result, unsafe.Pointer(f))
+	}
+
+	return result
+}
+
+// ListOptions returns options of FmtCtx, CodecCtx, SwsCtx, SwrCtx, BitstreamFilter,
+// or of the configured FilterGraph (Filter), including options of the child objects,
+// e.g. codec or muxer private options, or options of every filter of the graph.
+func ListOptions(obj interface{}) ([]*OptionInfo, error) {
+	ptr, err := optionsObj(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := listOptions(ptr, true)
+
+	for _, f := range graphFilters(obj, ptr) {
+		result = append(result, listOptions(f, true)...)
+	}
+
+	return result, nil
+}
+
+// FilterOptions returns options of the filter by name, e.g. "scale", without creating it.
+func FilterOptions(name string) ([]*OptionInfo, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	if C.avfilter_get_by_name(cname) == nil {
+		return nil, newAVError(AVERROR_FILTER_NOT_FOUND, "Unable to find filter", name)
+	}
+
+	// AVClass pointer is a fake object for av_opt_next
+	cls := C.gmf_opt_filter_class(cname)
+	if cls == nil {
+		return []*OptionInfo{}, nil
+	}
+
+	return listOptions(unsafe.Pointer(&cls), false), nil
+}
+
+func listOptions(obj unsafe.Pointer, withValues bool) []*OptionInfo {
+	result := make([]*OptionInfo, 0)
+	constants := make(map[string][]OptionConstant)
+	class := C.GoString(C.gmf_opt_class_name(obj))
+
+	for o := C.av_opt_next(obj, nil); o != nil; o = C.av_opt_next(obj, o) {
+		if int(o._type) == AV_OPT_TYPE_CONST {
+			unit := C.GoString(o.unit)
+			constants[unit] = append(constants[unit], OptionConstant{
+				Name:  C.GoString(o.name),
+				Help:  C.GoString(o.help),
+				Value: int64(C.gmf_opt_default_i64(o)),
+			})
+			continue
+		}
+
+		info := &OptionInfo{
+			Name:    C.GoString(o.name),
+			Help:    C.GoString(o.help),
+			Class:   class,
+			Type:    int(o._type),
+			Unit:    C.GoString(o.unit),
+			Flags:   int(o.flags),
+			Min:     float64(o.min),
+			Max:     float64(o.max),
+			Default: optionDefault(o),
+		}
+
+		if withValues {
+			info.Value, _ = getOption(obj, o, 0)
+		}
+
+		result = append(result, info)
+	}
+
+	for _, info := range result {
+		if info.Unit != "" {
+			info.Constants = constants[info.Unit]
+		}
+	}
+
+	if withValues {
+		for child := C.av_opt_child_next(obj, nil); child != nil; child = C.av_opt_child_next(obj, child) {
+			result = append(result, listOptions(child, true)...)
+		}
+	}
+
+	return result
+}
+
+func optionDefault(o *C.AVOption) interface{} {
+	if str := C.gmf_opt_default_str(o); str != nil {
+		return C.GoString(str)
+	}
+
+	i64, dbl := int64(C.gmf_opt_default_i64(o)), float64(C.gmf_opt_default_dbl(o))
+
+	switch int(o._type) {
+	case AV_OPT_TYPE_BOOL:
+		if i64 < 0 {
+			return nil
+		}
+		return i64 != 0
+	case AV_OPT_TYPE_FLAGS, AV_OPT_TYPE_INT, AV_OPT_TYPE_INT64, AV_OPT_TYPE_UINT64:
+		return i64
+	case AV_OPT_TYPE_PIXEL_FMT, AV_OPT_TYPE_SAMPLE_FMT:
+		return int32(i64)
+	case AV_OPT_TYPE_DURATION:
+		return time.Duration(i64) * time.Microsecond
+	case AV_OPT_TYPE_DOUBLE, AV_OPT_TYPE_FLOAT:
+		return dbl
+	case AV_OPT_TYPE_RATIONAL:
+		q := C.av_d2q(C.double(dbl), C.INT_MAX)
+		return AVR{Num: int(q.num), Den: int(q.den)}
+	case AV_OPT_TYPE_CHLAYOUT:
+		// legacy channel layout mask
+		return i64
+	}
+
+	return nil
+}
+
+// getOption returns typed value of the option o of the obj.
+func getOption(obj unsafe.Pointer, o *C.AVOption, searchFlags C.int) (interface{}, error) {
+	var (
+		ret int
+		i64 C.int64_t
+		dbl C.double
+		q   C.AVRational
+		str *C.uint8_t
+	)
+
+	switch int(o._type) {
+	case AV_OPT_TYPE_BOOL, AV_OPT_TYPE_FLAGS, AV_OPT_TYPE_INT, AV_OPT_TYPE_INT64, AV_OPT_TYPE_UINT64,
+		AV_OPT_TYPE_PIXEL_FMT, AV_OPT_TYPE_SAMPLE_FMT, AV_OPT_TYPE_DURATION:
+		if ret = int(C.av_opt_get_int(obj, o.name, searchFlags, &i64)); ret < 0 {
+			return nil, AvError(ret)
+		}
+
+		switch int(o._type) {
+		case AV_OPT_TYPE_BOOL:
+			if i64 < 0 {
+				return nil, nil
+			}
+			return i64 != 0, nil
+		case AV_OPT_TYPE_PIXEL_FMT, AV_OPT_TYPE_SAMPLE_FMT:
+			return int32(i64), nil
+		case AV_OPT_TYPE_DURATION:
+			return time.Duration(i64) * time.Microsecond, nil
+		}
+
+		return int64(i64), nil
+
+	case AV_OPT_TYPE_DOUBLE, AV_OPT_TYPE_FLOAT:
+		if ret = int(C.av_opt_get_double(obj, o.name, searchFlags, &dbl)); ret < 0 {
+			return nil, AvError(ret)
+		}
+
+		return float64(dbl), nil
+
+	case AV_OPT_TYPE_RATIONAL:
+		if ret = int(C.av_opt_get_q(obj, o.name, searchFlags, &q)); ret < 0 {
+			return nil, AvError(ret)
+		}
+
+		return AVR{Num: int(q.num), Den: int(q.den)}, nil
+	}
+
+	if ret = int(C.av_opt_get(obj, o.name, searchFlags, &str)); ret < 0 {
+		return nil, AvError(ret)
+	}
+	defer C.av_free(unsafe.Pointer(str))
+
+	if str == nil {
+		return "", nil
+	}
+
+	return C.GoString((*C.char)(unsafe.Pointer(str))), nil
+}
+
+// findOption returns the option and the object it belongs to. Options of the graph
+// filters are found by "filter.key", where filter is the filter instance name,
+// e.g. "Parsed_scale_0", or the filter name, e.g. "scale", or by "key" in any filter.
+func findOption(obj interface{}, key string) (unsafe.Pointer, *C.AVOption, error) {
+	ptr, err := optionsObj(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	find := func(target unsafe.Pointer, name string) (unsafe.Pointer, *C.AVOption) {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+
+		var found unsafe.Pointer
+
+		o := C.gmf_opt_find(target, cname, &found)
+		if o == nil || int(o._type) == AV_OPT_TYPE_CONST {
+			return nil, nil
+		}
+
+		return found, o
+	}
+
+	if target, o := find(ptr, key); o != nil {
+		return target, o, nil
+	}
+
+	prefix, name := "", key
+	if i := strings.Index(key, "."); i > 0 {
+		prefix, name = key[:i], key[i+1:]
+	}
+
+	for _, f := range graphFilters(obj, ptr) {
+		ctx := (*C.AVFilterContext)(f)
+
+		if prefix != "" && prefix != C.GoString(ctx.name) && prefix != C.GoString(ctx.filter.name) {
+			continue
+		}
+
+		if target, o := find(f, name); o != nil {
+			return target, o, nil
+		}
+	}
+
+	return nil, nil, newAVError(AVERROR_OPTION_NOT_FOUND, "Unable to find option", key)
+}
+
+// GetOption returns the typed value of the option, see OptionInfo for the types.
+func GetOption(obj interface{}, key string) (interface{}, error) {
+	target, o, err := findOption(obj, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return getOption(target, o, 0)
+}
+
+// SetOption sets the option value. Value may be a string, parsed by FFmpeg like
+// the command line value (e.g. "fast" or "+global_header"), bool, int, int32, int64,
+// float64, AVR, time.Duration, *ChannelLayout or *Dict. Numeric values are checked
+// against the option range. Options of the opened codecs or configured filters
+// may be ignored, they should be set before initialization.
+func SetOption(obj interface{}, key string, val interface{}) error {
+	target, o, err := findOption(obj, key)
+	if err != nil {
+		return err
+	}
+
+	if int(o.flags)&AV_OPT_FLAG_READONLY != 0 {
+		return newAVError(-int(syscall.EINVAL), fmt.Sprintf("Option '%s' is read only", key), "")
+	}
+
+	checkRange := func(v float64) error {
+		if min, max := float64(o.min), float64(o.max); min < max && (v < min || v > max) {
+			return newAVError(-int(syscall.ERANGE), fmt.Sprintf("Value %v of option '%s' is out of range [%v - %v]", val, key, min, max), "")
+		}
+
+		return nil
+	}
+
+	var ret int
+
+	switch v := val.(type) {
+	case string:
+		cval := C.CString(v)
+		defer C.free(unsafe.Pointer(cval))
+
+		ret = int(C.av_opt_set(target, o.name, cval, 0))
+
+	case bool:
+		var i int64
+		if v {
+			i = 1
+		}
+		ret = int(C.av_opt_set_int(target, o.name, C.int64_t(i), 0))
+
+	case int, int32, int64:
+		i := reflect.ValueOf(v).Int()
+		if err := checkRange(float64(i)); err != nil {
+			return err
+		}
+		ret = int(C.av_opt_set_int(target, o.name, C.int64_t(i), 0))
+
+	case float64:
+		if err := checkRange(v); err != nil {
+			return err
+		}
+		ret = int(C.av_opt_set_double(target, o.name, C.double(v), 0))
+
+	case AVR:
+		if v.Den != 0 {
+			if err := checkRange(v.Av2qd()); err != nil {
+				return err
+			}
+		}
+		ret = int(C.av_opt_set_q(target, o.name, C.struct_AVRational(v.AVRational()), 0))
+
+	case time.Duration:
+		if err := checkRange(float64(v / time.Microsecond)); err != nil {
+			return err
+		}
+		ret = int(C.av_opt_set_int(target, o.name, C.int64_t(v/time.Microsecond), 0))
+
+	case *ChannelLayout:
+		ret = v.setOption(target, o.name)
+
+	case *Dict:
+		ret = int(C.av_opt_set_dict_val(target, o.name, v.dict, 0))
+
+	default:
+		return errors.New(fmt.Sprintf("Unsupported type '%T' of option '%s' value", val, key))
+	}
+
+	if ret < 0 {
+		return newAVError(ret, fmt.Sprintf("Unable to set option '%s' to '%v'", key, val), "")
+	}
+
+	return nil
+}
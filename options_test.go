@@ -1,7 +1,9 @@
 package gmf
 
 import (
+	"bytes"
 	"log"
+	"syscall"
 	"testing"
 )
 
@@ -36,3 +38,242 @@ func TestOptionSet(t *testing.T) {
 
 	log.Println("Options work")
 }
+
+func TestListOptions(t *testing.T) {
+	codec, err := FindEncoder("mpeg4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := NewCodecCtx(codec)
+	if cc == nil {
+		t.Fatal("Unable to allocate codec context")
+	}
+	defer cc.Free()
+
+	options, err := ListOptions(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]*OptionInfo)
+	for _, o := range options {
+		found[o.Name] = o
+	}
+
+	if b, ok := found["b"]; !ok || b.Type != AV_OPT_TYPE_INT64 || b.Default == nil {
+		t.Fatalf("Expected int64 option 'b' with default, %+v got\n", b)
+	}
+
+	if flags, ok := found["flags"]; !ok || len(flags.Constants) == 0 {
+		t.Fatalf("Expected option 'flags' with constants, %+v got\n", flags)
+	}
+
+	// mpeg4 private options
+	if _, ok := found["data_partitioning"]; !ok {
+		t.Fatalf("Expected private option 'data_partitioning'\n")
+	}
+
+	if err := SetOption(cc, "b", 400000); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := GetOption(cc, "b"); err != nil || val != int64(400000) {
+		t.Fatalf("Expected bit rate 400000, %v (%v) got\n", val, err)
+	}
+
+	if err := SetOption(cc, "b", -1); err == nil {
+		t.Fatalf("Expected error for out of range value\n")
+	}
+
+	if err := SetOption(cc, "not_existing", 1); !isAVError(err, AVERROR_OPTION_NOT_FOUND) {
+		t.Fatalf("Expected ErrOptionNotFound, %v got\n", err)
+	}
+
+	if _, err := GetOption(cc, "not_existing"); !isAVError(err, AVERROR_OPTION_NOT_FOUND) {
+		t.Fatalf("Expected ErrOptionNotFound, %v got\n", err)
+	}
+}
+
+func TestFilterOptions(t *testing.T) {
+	options, err := FilterOptions("scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, o := range options {
+		if o.Name == "w" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("Expected option 'w' of scale filter\n")
+	}
+
+	if _, err := FilterOptions("not_existing"); err == nil {
+		t.Fatalf("Expected error for not existing filter\n")
+	}
+}
+
+func TestFmtCtxOptions(t *testing.T) {
+	outputCtx, err := NewOutputCtxToWriter(new(bytes.Buffer), "mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	if err := SetOption(outputCtx, "max_delay", 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := GetOption(outputCtx, "max_delay"); err != nil || val != int64(1000) {
+		t.Fatalf("Expected max_delay 1000, %v (%v) got\n", val, err)
+	}
+
+	// muxer private option
+	if err := SetOption(outputCtx, "movflags", "+faststart"); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := GetOption(outputCtx, "movflags"); err != nil || val == int64(0) {
+		t.Fatalf("Expected movflags set, %v (%v) got\n", val, err)
+	}
+}
+
+func TestSwsCtxOptions(t *testing.T) {
+	swsCtx, err := NewSwsCtx(320, 200, AV_PIX_FMT_YUV420P, 160, 100, AV_PIX_FMT_RGBA, SWS_BICUBIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer swsCtx.Free()
+
+	if val, err := GetOption(swsCtx, "dstw"); err != nil || val != int64(160) {
+		t.Fatalf("Expected dstw 160, %v (%v) got\n", val, err)
+	}
+
+	if options, err := ListOptions(swsCtx); err != nil || len(options) == 0 {
+		t.Fatalf("Expected swscale options, %d (%v) got\n", len(options), err)
+	}
+}
+
+func TestSwrCtxOptions(t *testing.T) {
+	layout := NewDefaultChannelLayout(2)
+	defer layout.Free()
+
+	swrCtx, err := NewSwrCtxWithLayout([]*Option{
+		{"in_chlayout", layout},
+		{"in_sample_rate", 44100},
+		{"in_sample_fmt", AV_SAMPLE_FMT_S16},
+		{"out_chlayout", layout},
+		{"out_sample_rate", 48000},
+		{"out_sample_fmt", AV_SAMPLE_FMT_FLTP},
+	}, layout, AV_SAMPLE_FMT_FLTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer swrCtx.Free()
+
+	if val, err := GetOption(swrCtx, "out_sample_rate"); err != nil || val != int64(48000) {
+		t.Fatalf("Expected out_sample_rate 48000, %v (%v) got\n", val, err)
+	}
+
+	if val, err := GetOption(swrCtx, "in_sample_fmt"); err != nil || val != AV_SAMPLE_FMT_S16 {
+		t.Fatalf("Expected in_sample_fmt s16, %v (%v) got\n", val, err)
+	}
+}
+
+func TestFilterGraphOptions(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	f, err := NewFilter("scale=w=160:h=100", []*Stream{ist}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Release()
+
+	options, err := ListOptions(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, o := range options {
+		if o.Name == "w" && o.Value == "160" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("Expected option 'w' of the scale filter with value 160\n")
+	}
+
+	// by the filter name, the instance name or any filter
+	for _, key := range []string{"scale.w", "Parsed_scale_0.w", "w"} {
+		if val, err := GetOption(f, key); err != nil || val != "160" {
+			t.Fatalf("Expected '%s' 160, %v (%v) got\n", key, val, err)
+		}
+	}
+
+	if _, err := GetOption(f, "hflip.w"); !isAVError(err, AVERROR_OPTION_NOT_FOUND) {
+		t.Fatalf("Expected ErrOptionNotFound for not existing filter, %v got\n", err)
+	}
+
+	if err := SetOption(f, "scale.h", "50"); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := GetOption(f, "Parsed_scale_0.h"); err != nil || val != "50" {
+		t.Fatalf("Expected 'h' 50, %v (%v) got\n", val, err)
+	}
+}
+
+func TestSetOptionReadOnly(t *testing.T) {
+	codec, err := FindDecoder("h264")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := NewCodecCtx(codec)
+	if cc == nil {
+		t.Fatal("Unable to allocate codec context")
+	}
+	defer cc.Free()
+
+	outputCtx, err := NewOutputCtxToWriter(new(bytes.Buffer), "mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	// read only options depend on FFmpeg version, the first one found is checked
+	for _, obj := range []interface{}{cc, outputCtx} {
+		options, err := ListOptions(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, o := range options {
+			if !o.IsReadOnly() {
+				continue
+			}
+
+			err := SetOption(obj, o.Name, "0")
+
+			if !isAVError(err, -int(syscall.EINVAL)) {
+				t.Fatalf("Expected EINVAL AVError for read only option '%s', %v got\n", o.Name, err)
+			}
+
+			return
+		}
+	}
+
+	t.Skip("No read only options found")
+}
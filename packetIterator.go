@@ -0,0 +1,149 @@
+package gmf
+
+import (
+	"context"
+	"sync"
+)
+
+type packetResult struct {
+	pkt *Packet
+	err error
+}
+
+// PacketIterator reads packets from an input context, one per Next call.
+// Clean end of the input is not an error, Err returns nil in this case.
+//
+// E.g.:
+//
+//	it := inputCtx.Packets(0)
+//	defer it.Close()
+//
+//	for it.Next() {
+//		pkt := it.Packet()
+//		... processing ...
+//		pkt.Free()
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		... input is failed ...
+//	}
+type PacketIterator struct {
+	fmtCtx  *FmtCtx
+	ctx     context.Context
+	cancel  context.CancelFunc
+	results chan packetResult
+	wg      sync.WaitGroup
+	pkt     *Packet
+	err     error
+	done    bool
+}
+
+// Packets returns an iterator over the input packets. If readAhead > 0,
+// packets are read in a separate goroutine into a buffer of readAhead packets,
+// otherwise they are read synchronously inside Next.
+func (this *FmtCtx) Packets(readAhead int) *PacketIterator {
+	return this.PacketsWithContext(context.Background(), readAhead)
+}
+
+// PacketsWithContext is the same as Packets, iteration is stopped with
+// ctx.Err() as soon as ctx is done.
+func (this *FmtCtx) PacketsWithContext(ctx context.Context, readAhead int) *PacketIterator {
+	it := &PacketIterator{fmtCtx: this}
+
+	it.ctx, it.cancel = context.WithCancel(ctx)
+
+	if readAhead > 0 {
+		it.results = make(chan packetResult, readAhead)
+		it.wg.Add(1)
+		go it.readLoop()
+	}
+
+	return it
+}
+
+func (it *PacketIterator) readLoop() {
+	defer it.wg.Done()
+	defer close(it.results)
+
+	for {
+		pkt, err := it.fmtCtx.ReadPacket(it.ctx)
+
+		select {
+		case it.results <- packetResult{pkt: pkt, err: err}:
+		case <-it.ctx.Done():
+			if pkt != nil {
+				pkt.Free()
+			}
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Next advances the iterator to the next packet. It returns false when
+// the input is finished, failed or the iterator is closed.
+func (it *PacketIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	var res packetResult
+
+	if it.results == nil {
+		res.pkt, res.err = it.fmtCtx.ReadPacket(it.ctx)
+	} else {
+		var ok bool
+
+		if res, ok = <-it.results; !ok {
+			it.done = true
+			return false
+		}
+	}
+
+	if res.err != nil {
+		it.pkt = nil
+		it.done = true
+
+		if !isEOF(res.err) {
+			it.err = res.err
+		}
+
+		return false
+	}
+
+	it.pkt = res.pkt
+
+	return true
+}
+
+// Packet returns the packet read by the last Next call.
+// The caller owns the packet and has to Free it.
+func (it *PacketIterator) Packet() *Packet {
+	return it.pkt
+}
+
+// Err returns the first error, which stopped the iteration,
+// or nil if the input has been read till the end.
+func (it *PacketIterator) Err() error {
+	return it.err
+}
+
+// Close stops the reading goroutine and frees all the packets read ahead.
+// It is safe to call Close several times.
+func (it *PacketIterator) Close() {
+	it.cancel()
+	it.wg.Wait()
+
+	if it.results != nil {
+		for res := range it.results {
+			if res.pkt != nil {
+				res.pkt.Free()
+			}
+		}
+	}
+
+	it.done = true
+}
@@ -22,6 +22,13 @@ import (
 	"unsafe"
 )
 
+const (
+	AV_PKT_FLAG_KEY        int = C.AV_PKT_FLAG_KEY
+	AV_PKT_FLAG_CORRUPT    int = C.AV_PKT_FLAG_CORRUPT
+	AV_PKT_FLAG_DISCARD    int = C.AV_PKT_FLAG_DISCARD
+	AV_PKT_FLAG_DISPOSABLE int = C.AV_PKT_FLAG_DISPOSABLE
+)
+
 type Packet struct {
 	avPacket C.struct_AVPacket
 }
@@ -64,6 +71,15 @@ func (p *Packet) Flags() int {
 	return int(p.avPacket.flags)
 }
 
+func (p *Packet) SetFlags(val int) *Packet {
+	p.avPacket.flags = C.int(val)
+	return p
+}
+
+func (p *Packet) IsKey() bool {
+	return int(p.avPacket.flags)&AV_PKT_FLAG_KEY != 0
+}
+
 func (p *Packet) Duration() int64 {
 	return int64(p.avPacket.duration)
 }
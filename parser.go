@@ -0,0 +1,196 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavcodec libavutil
+
+#include <string.h>
+#include "libavcodec/avcodec.h"
+#include "libavutil/mem.h"
+
+static int gmf_parser_parse(AVCodecParserContext *s, AVCodecContext *avctx, uint8_t **out, int *out_size,
+                            uint8_t *buf, int offset, int size, int64_t pts, int64_t dts, int64_t pos) {
+	return av_parser_parse2(s, avctx, out, out_size, buf ? buf + offset : NULL, size, pts, dts, pos);
+}
+
+static uint8_t *gmf_parser_buffer(uint8_t *buf, int size) {
+	av_free(buf);
+
+	return av_malloc(size + AV_INPUT_BUFFER_PADDING_SIZE);
+}
+
+static void gmf_parser_fill(uint8_t *buf, void *data, int size) {
+	memcpy(buf, data, size);
+	memset(buf + size, 0, AV_INPUT_BUFFER_PADDING_SIZE);
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Parser splits elementary stream (e.g. raw H.264 or ADTS AAC) into packets,
+// suitable for CodecCtx.Decode.
+type Parser struct {
+	avParser *C.struct_AVCodecParserContext
+	cc       *CodecCtx
+	buf      *C.uint8_t
+	bufSize  int
+	CgoMemoryManage
+}
+
+// NewParser creates a parser for the codec of cc.
+func NewParser(cc *CodecCtx) (*Parser, error) {
+	if cc == nil || cc.avCodecCtx == nil {
+		return nil, errors.New("codec context is not initialized")
+	}
+
+	avParser := C.av_parser_init(C.int(cc.Id()))
+	if avParser == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to find parser for codec id '%d'", cc.Id()))
+	}
+
+	return &Parser{avParser: avParser, cc: cc}, nil
+}
+
+func (this *Parser) Free() {
+	if this.avParser != nil {
+		C.av_parser_close(this.avParser)
+		this.avParser = nil
+	}
+
+	if this.buf != nil {
+		C.av_free(unsafe.Pointer(this.buf))
+		this.buf = nil
+	}
+}
+
+// Parse consumes a chunk of arbitrary size and returns all the packets
+// completed by this chunk. Timestamps are unknown.
+func (this *Parser) Parse(data []byte) ([]*Packet, error) {
+	return this.ParseWithTs(data, AV_NOPTS_VALUE, AV_NOPTS_VALUE, -1)
+}
+
+// ParseWithTs is the same as Parse, pts, dts and pos are those of the chunk,
+// they are assigned to the packet which starts in this chunk. Unknown
+// timestamps are AV_NOPTS_VALUE, unknown pos is -1.
+func (this *Parser) ParseWithTs(data []byte, pts, dts, pos int64) ([]*Packet, error) {
+	if len(data) == 0 {
+		return make([]*Packet, 0), nil
+	}
+
+	if len(data) > this.bufSize {
+		if this.buf = C.gmf_parser_buffer(this.buf, C.int(len(data))); this.buf == nil {
+			this.bufSize = 0
+			return nil, errors.New(fmt.Sprintf("Unable to allocate %d bytes for parser buffer", len(data)))
+		}
+		this.bufSize = len(data)
+	}
+
+	C.gmf_parser_fill(this.buf, unsafe.Pointer(&data[0]), C.int(len(data)))
+
+	return this.parse(this.buf, len(data), pts, dts, pos)
+}
+
+// Flush returns the last packet, which is kept by parser till the end of the stream.
+func (this *Parser) Flush() ([]*Packet, error) {
+	return this.parse(nil, 0, AV_NOPTS_VALUE, AV_NOPTS_VALUE, -1)
+}
+
+func (this *Parser) parse(buf *C.uint8_t, size int, pts, dts, pos int64) ([]*Packet, error) {
+	var (
+		out     *C.uint8_t
+		outSize C.int
+		offset  int
+		result  []*Packet = make([]*Packet, 0)
+	)
+
+	for {
+		ret := int(C.gmf_parser_parse(this.avParser, this.cc.avCodecCtx, &out, &outSize,
+			buf, C.int(offset), C.int(size-offset), C.int64_t(pts), C.int64_t(dts), C.int64_t(pos)))
+		if ret < 0 {
+			freePackets(result)
+			return nil, newAVError(ret, "Unable to parse", this.cc.Codec().Name())
+		}
+
+		offset += ret
+
+		// timestamps belong to the chunk start, the rest of it has none
+		pts, dts, pos = AV_NOPTS_VALUE, AV_NOPTS_VALUE, -1
+
+		if outSize > 0 {
+			pkt, err := this.newPacket(out, int(outSize))
+			if err != nil {
+				freePackets(result)
+				return nil, err
+			}
+
+			result = append(result, pkt)
+		}
+
+		if offset >= size || (ret == 0 && outSize == 0) {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func (this *Parser) newPacket(data *C.uint8_t, size int) (*Packet, error) {
+	p := NewPacket()
+
+	if ret := int(C.av_new_packet(&p.avPacket, C.int(size))); ret < 0 {
+		return nil, AvError(ret)
+	}
+
+	C.memcpy(unsafe.Pointer(p.avPacket.data), unsafe.Pointer(data), C.size_t(size))
+
+	p.avPacket.pts = this.avParser.pts
+	p.avPacket.dts = this.avParser.dts
+	p.avPacket.pos = this.avParser.pos
+
+	if this.avParser.duration > 0 {
+		p.avPacket.duration = C.int64_t(this.avParser.duration)
+	}
+
+	if this.avParser.key_frame == 1 {
+		p.avPacket.flags |= C.AV_PKT_FLAG_KEY
+	}
+
+	return p, nil
+}
+
+// KeyFrame returns 1 if the last packet is a keyframe, 0 if it isn't, -1 if unknown.
+func (this *Parser) KeyFrame() int {
+	return int(this.avParser.key_frame)
+}
+
+func (this *Parser) PictType() int {
+	return int(this.avParser.pict_type)
+}
+
+// Width and Height are known, once the parser has seen the stream headers.
+func (this *Parser) Width() int {
+	return int(this.avParser.width)
+}
+
+func (this *Parser) Height() int {
+	return int(this.avParser.height)
+}
+
+func (this *Parser) CodedWidth() int {
+	return int(this.avParser.coded_width)
+}
+
+func (this *Parser) CodedHeight() int {
+	return int(this.avParser.coded_height)
+}
+
+// AVPixelFormat for video, AVSampleFormat for audio, -1 if unknown.
+func (this *Parser) Format() int {
+	return int(this.avParser.format)
+}
@@ -0,0 +1,123 @@
+package gmf
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestParser(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	// mpeg4 packets in mp4 are raw m4v frames, so joined together
+	// they make an elementary stream.
+	es := new(bytes.Buffer)
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		es.Write(it.Packet().Data())
+		it.Packet().Free()
+	}
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	parser, err := NewParser(ist.CodecCtx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parser.Free()
+
+	var (
+		data = es.Bytes()
+		cnt  = 0
+	)
+
+	for len(data) > 0 {
+		n := rand.Intn(4096) + 1
+		if n > len(data) {
+			n = len(data)
+		}
+
+		pkts, err := parser.Parse(data[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, pkt := range pkts {
+			if pkt.Pos() != -1 {
+				t.Fatalf("Expected unknown pos -1, %d got\n", pkt.Pos())
+			}
+		}
+
+		cnt += len(pkts)
+		freePackets(pkts)
+
+		data = data[n:]
+	}
+
+	pkts, err := parser.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt += len(pkts)
+	freePackets(pkts)
+
+	if cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+
+	if parser.Width() != inputSampleWidth || parser.Height() != inputSampleHeight {
+		t.Fatalf("Expected dimension = %dx%d, %dx%d got\n", inputSampleWidth, inputSampleHeight, parser.Width(), parser.Height())
+	}
+}
+
+func TestParserWithTs(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	es := new(bytes.Buffer)
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		es.Write(it.Packet().Data())
+		it.Packet().Free()
+	}
+
+	parser, err := NewParser(assert(inputCtx.GetStream(0)).(*Stream).CodecCtx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parser.Free()
+
+	// the whole stream is a single chunk, only its first packet has timestamps
+	pkts, err := parser.ParseWithTs(es.Bytes(), 1000, 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer freePackets(pkts)
+
+	if len(pkts) < 2 {
+		t.Fatalf("Expected packets parsed, %d got\n", len(pkts))
+	}
+
+	if pkts[0].Pts() != 1000 || pkts[0].Dts() != 1000 || pkts[0].Pos() != 0 {
+		t.Fatalf("Expected pts, dts 1000 and pos 0, %d, %d, %d got\n", pkts[0].Pts(), pkts[0].Dts(), pkts[0].Pos())
+	}
+
+	for i, pkt := range pkts[1:] {
+		if pkt.Pts() != AV_NOPTS_VALUE || pkt.Pos() != -1 {
+			t.Fatalf("Expected packet %d without timestamps, pts %d, pos %d got\n", i+1, pkt.Pts(), pkt.Pos())
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavcodec libavutil
+
+#include "libavformat/avformat.h"
+#include "libavcodec/avcodec.h"
+#include "libavutil/channel_layout.h"
+#include "libavutil/display.h"
+#include "libavutil/mem.h"
+#include "libavutil/pixdesc.h"
+
+static AVProgram *gmf_probe_program(AVFormatContext *ctx, int idx) {
+	return ctx->programs[idx];
+}
+
+static int gmf_probe_program_stream(AVProgram *program, int idx) {
+	return (int)program->stream_index[idx];
+}
+
+static int gmf_probe_nb_side_data(AVStream *st) {
+#if LIBAVFORMAT_VERSION_MAJOR >= 61
+	return st->codecpar->nb_coded_side_data;
+#else
+	return st->nb_side_data;
+#endif
+}
+
+static AVPacketSideData *gmf_probe_side_data(AVStream *st, int idx) {
+#if LIBAVFORMAT_VERSION_MAJOR >= 61
+	return &st->codecpar->coded_side_data[idx];
+#else
+	return &st->side_data[idx];
+#endif
+}
+
+static int gmf_probe_rotation(AVPacketSideData *sd) {
+	if (sd->type != AV_PKT_DATA_DISPLAYMATRIX || sd->size < 9 * 4)
+		return 0;
+
+	return (int)av_display_rotation_get((int32_t *)sd->data);
+}
+
+static AVRational gmf_probe_dar(AVRational sar, int width, int height) {
+	AVRational dar = { 0, 1 };
+
+	if (sar.num)
+		av_reduce(&dar.num, &dar.den, (int64_t)width * sar.num, (int64_t)height * sar.den, 1024 * 1024);
+
+	return dar;
+}
+
+static int gmf_probe_channels(AVCodecParameters *par) {
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+	return par->ch_layout.nb_channels;
+#else
+	return par->channels;
+#endif
+}
+
+static char *gmf_probe_channel_layout(AVCodecParameters *par) {
+	char *buf = av_mallocz(128);
+
+	if (buf)
+#if LIBAVUTIL_VERSION_INT >= AV_VERSION_INT(57, 24, 100)
+		av_channel_layout_describe(&par->ch_layout, buf, 128);
+#else
+		av_get_channel_layout_string(buf, 128, par->channels, par->channel_layout);
+#endif
+
+	return buf;
+}
+
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// MediaInfo is a result of Probe. It is marshaled to JSON of the same schema,
+// as the output of `ffprobe -of json -show_format -show_streams -show_chapters -show_programs`.
+type MediaInfo struct {
+	Programs []ProgramInfo `json:"programs,omitempty"`
+	Streams  []StreamInfo  `json:"streams"`
+	Chapters []ChapterInfo `json:"chapters,omitempty"`
+	Format   FormatInfo    `json:"format"`
+}
+
+// Seconds is a time in seconds. It's marshaled to JSON the same way as
+// by ffprobe, a string of "%f" format, e.g. "0.000000".
+type Seconds float64
+
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("\"%f\"", float64(s))), nil
+}
+
+func (s *Seconds) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseFloat(strings.Trim(string(b), "\""), 64)
+	if err != nil {
+		return err
+	}
+
+	*s = Seconds(v)
+
+	return nil
+}
+
+func newSeconds(v float64) *Seconds {
+	s := Seconds(v)
+	return &s
+}
+
+// FormatInfo times are nil, if they are unknown, ffprobe omits them as well.
+// Integer values are omitted, if they are zero, the same as "N/A" of ffprobe.
+type FormatInfo struct {
+	Filename       string            `json:"filename"`
+	NbStreams      int               `json:"nb_streams"`
+	NbPrograms     int               `json:"nb_programs"`
+	FormatName     string            `json:"format_name"`
+	FormatLongName string            `json:"format_long_name,omitempty"`
+	StartTime      *Seconds          `json:"start_time,omitempty"`
+	Duration       *Seconds          `json:"duration,omitempty"`
+	Size           int64             `json:"size,omitempty,string"`
+	BitRate        int64             `json:"bit_rate,omitempty,string"`
+	ProbeScore     int               `json:"probe_score"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+type StreamInfo struct {
+	Index          int    `json:"index"`
+	CodecName      string `json:"codec_name,omitempty"`
+	CodecLongName  string `json:"codec_long_name,omitempty"`
+	Profile        string `json:"profile,omitempty"`
+	CodecType      string `json:"codec_type"`
+	CodecTagString string `json:"codec_tag_string"`
+	CodecTag       string `json:"codec_tag"`
+
+	// video
+	Width              int    `json:"width,omitempty"`
+	Height             int    `json:"height,omitempty"`
+	HasBFrames         int    `json:"has_b_frames,omitempty"`
+	SampleAspectRatio  string `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string `json:"display_aspect_ratio,omitempty"`
+	PixFmt             string `json:"pix_fmt,omitempty"`
+	Level              int    `json:"level,omitempty"`
+	ColorRange         string `json:"color_range,omitempty"`
+	ColorSpace         string `json:"color_space,omitempty"`
+	ColorTransfer      string `json:"color_transfer,omitempty"`
+	ColorPrimaries     string `json:"color_primaries,omitempty"`
+	ChromaLocation     string `json:"chroma_location,omitempty"`
+	FieldOrder         string `json:"field_order,omitempty"`
+
+	// audio
+	SampleFmt     string `json:"sample_fmt,omitempty"`
+	SampleRate    int    `json:"sample_rate,omitempty,string"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	BitsPerSample int    `json:"bits_per_sample,omitempty"`
+
+	Id               string            `json:"id,omitempty"`
+	RFrameRate       string            `json:"r_frame_rate"`
+	AvgFrameRate     string            `json:"avg_frame_rate"`
+	TimeBase         string            `json:"time_base"`
+	StartPts         int64             `json:"start_pts,omitempty"`
+	StartTime        *Seconds          `json:"start_time,omitempty"`
+	DurationTs       int64             `json:"duration_ts,omitempty"`
+	Duration         *Seconds          `json:"duration,omitempty"`
+	BitRate          int64             `json:"bit_rate,omitempty,string"`
+	BitsPerRawSample int               `json:"bits_per_raw_sample,omitempty,string"`
+	NbFrames         int64             `json:"nb_frames,omitempty,string"`
+	Disposition      map[string]int    `json:"disposition"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	SideDataList     []SideDataInfo    `json:"side_data_list,omitempty"`
+}
+
+// Language returns ISO 639-2 language code of the stream, if it's known.
+func (s *StreamInfo) Language() string {
+	return s.Tags["language"]
+}
+
+type SideDataInfo struct {
+	SideDataType string `json:"side_data_type"`
+	Rotation     int    `json:"rotation,omitempty"`
+}
+
+type ChapterInfo struct {
+	Id        int64             `json:"id"`
+	TimeBase  string            `json:"time_base"`
+	Start     int64             `json:"start"`
+	StartTime Seconds           `json:"start_time"`
+	End       int64             `json:"end"`
+	EndTime   Seconds           `json:"end_time"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+type ProgramInfo struct {
+	ProgramId  int               `json:"program_id"`
+	ProgramNum int               `json:"program_num"`
+	NbStreams  int               `json:"nb_streams"`
+	PmtPid     int               `json:"pmt_pid"`
+	PcrPid     int               `json:"pcr_pid"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Streams    []StreamInfo      `json:"streams"`
+}
+
+var probeFieldOrders = map[uint32]string{
+	C.AV_FIELD_PROGRESSIVE: "progressive",
+	C.AV_FIELD_TT:          "tt",
+	C.AV_FIELD_BB:          "bb",
+	C.AV_FIELD_TB:          "tb",
+	C.AV_FIELD_BT:          "bt",
+}
+
+// Probe opens the input, reads stream info and returns it as MediaInfo.
+// Options are the same as for NewInputCtxWithOption.
+func Probe(url string, options ...*Option) (*MediaInfo, error) {
+	ctx, err := NewInputCtxWithOption(url, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer ctx.Free()
+
+	return ctx.MediaInfo(), nil
+}
+
+// MediaInfo describes format, streams, chapters and programs of opened input context.
+func (this *FmtCtx) MediaInfo() *MediaInfo {
+	info := &MediaInfo{
+		Streams:  make([]StreamInfo, 0, this.StreamsCnt()),
+		Chapters: make([]ChapterInfo, 0, int(this.avCtx.nb_chapters)),
+		Programs: make([]ProgramInfo, 0, int(this.avCtx.nb_programs)),
+		Format:   this.formatInfo(),
+	}
+
+	for i := 0; i < this.StreamsCnt(); i++ {
+		st, err := this.GetStream(i)
+		if err != nil {
+			continue
+		}
+
+		info.Streams = append(info.Streams, this.streamInfo(st))
+	}
+
+	for _, ch := range this.Chapters() {
+		info.Chapters = append(info.Chapters, ChapterInfo{
+			Id:        ch.Id,
+			TimeBase:  ch.TimeBase.String(),
+			Start:     ch.Start,
+			StartTime: Seconds(ch.StartTime()),
+			End:       ch.End,
+			EndTime:   Seconds(ch.EndTime()),
+			Tags:      ch.Metadata,
+		})
+	}
+
+	for i := 0; i < int(this.avCtx.nb_programs); i++ {
+		program := C.gmf_probe_program(this.avCtx, C.int(i))
+
+		pi := ProgramInfo{
+			ProgramId:  int(program.id),
+			ProgramNum: int(program.program_num),
+			NbStreams:  int(program.nb_stream_indexes),
+			PmtPid:     int(program.pmt_pid),
+			PcrPid:     int(program.pcr_pid),
+			Tags:       (&Dict{dict: program.metadata}).Map(),
+			Streams:    make([]StreamInfo, 0, int(program.nb_stream_indexes)),
+		}
+
+		for j := 0; j < int(program.nb_stream_indexes); j++ {
+			idx := int(C.gmf_probe_program_stream(program, C.int(j)))
+			if idx < len(info.Streams) {
+				pi.Streams = append(pi.Streams, info.Streams[idx])
+			}
+		}
+
+		info.Programs = append(info.Programs, pi)
+	}
+
+	return info
+}
+
+func (this *FmtCtx) formatInfo() FormatInfo {
+	fi := FormatInfo{
+		Filename:   this.filename,
+		NbStreams:  this.StreamsCnt(),
+		NbPrograms: int(this.avCtx.nb_programs),
+		BitRate:    this.BitRate(),
+		ProbeScore: int(this.avCtx.probe_score),
+		Tags:       (&Dict{dict: this.avCtx.metadata}).Map(),
+	}
+
+	if this.avCtx.iformat != nil {
+		fi.FormatName = C.GoString(this.avCtx.iformat.name)
+		fi.FormatLongName = C.GoString(this.avCtx.iformat.long_name)
+	}
+
+	if int64(this.avCtx.start_time) != AV_NOPTS_VALUE {
+		fi.StartTime = newSeconds(float64(this.avCtx.start_time) / float64(AV_TIME_BASE))
+	}
+
+	if int64(this.avCtx.duration) != AV_NOPTS_VALUE {
+		fi.Duration = newSeconds(this.Duration())
+	}
+
+	if this.avCtx.pb != nil {
+		if size := int64(C.avio_size(this.avCtx.pb)); size > 0 {
+			fi.Size = size
+		}
+	}
+
+	return fi
+}
+
+func (this *FmtCtx) streamInfo(st *Stream) StreamInfo {
+	par := st.avStream.codecpar
+	tb := st.TimeBase().AVR()
+
+	si := StreamInfo{
+		Index:            st.Index(),
+		CodecType:        C.GoString(C.av_get_media_type_string(par.codec_type)),
+		CodecTagString:   fourccString(uint32(par.codec_tag)),
+		CodecTag:         fmt.Sprintf("0x%04x", uint32(par.codec_tag)),
+		RFrameRate:       st.GetRFrameRate().AVR().String(),
+		AvgFrameRate:     st.GetAvgFrameRate().AVR().String(),
+		TimeBase:         tb.String(),
+		BitRate:          int64(par.bit_rate),
+		BitsPerRawSample: int(par.bits_per_raw_sample),
+		NbFrames:         int64(st.avStream.nb_frames),
+		Disposition:      make(map[string]int, len(dispositionNames)),
+		Tags:             (&Dict{dict: st.avStream.metadata}).Map(),
+	}
+
+	if desc := C.avcodec_descriptor_get(par.codec_id); desc != nil {
+		si.CodecName = C.GoString(desc.name)
+		si.CodecLongName = C.GoString(desc.long_name)
+	}
+
+	if profile := C.avcodec_profile_name(par.codec_id, par.profile); profile != nil {
+		si.Profile = C.GoString(profile)
+	}
+
+	switch int32(par.codec_type) {
+	case AVMEDIA_TYPE_VIDEO:
+		si.Width = int(par.width)
+		si.Height = int(par.height)
+		si.HasBFrames = int(par.video_delay)
+		si.Level = int(par.level)
+
+		if sar := C.av_guess_sample_aspect_ratio(this.avCtx, st.avStream, nil); sar.num != 0 {
+			dar := C.gmf_probe_dar(sar, par.width, par.height)
+			si.SampleAspectRatio = fmt.Sprintf("%d:%d", int(sar.num), int(sar.den))
+			si.DisplayAspectRatio = fmt.Sprintf("%d:%d", int(dar.num), int(dar.den))
+		}
+
+		if name := C.av_get_pix_fmt_name(int32(par.format)); name != nil {
+			si.PixFmt = C.GoString(name)
+		}
+
+		if par.color_range != C.AVCOL_RANGE_UNSPECIFIED {
+			si.ColorRange = C.GoString(C.av_color_range_name(par.color_range))
+		}
+		if par.color_space != C.AVCOL_SPC_UNSPECIFIED {
+			si.ColorSpace = C.GoString(C.av_color_space_name(par.color_space))
+		}
+		if par.color_trc != C.AVCOL_TRC_UNSPECIFIED {
+			si.ColorTransfer = C.GoString(C.av_color_transfer_name(par.color_trc))
+		}
+		if par.color_primaries != C.AVCOL_PRI_UNSPECIFIED {
+			si.ColorPrimaries = C.GoString(C.av_color_primaries_name(par.color_primaries))
+		}
+		if par.chroma_location != C.AVCHROMA_LOC_UNSPECIFIED {
+			si.ChromaLocation = C.GoString(C.av_chroma_location_name(par.chroma_location))
+		}
+
+		si.FieldOrder = probeFieldOrders[uint32(par.field_order)]
+
+	case AVMEDIA_TYPE_AUDIO:
+		si.SampleRate = int(par.sample_rate)
+		si.Channels = int(C.gmf_probe_channels(par))
+		si.BitsPerSample = int(C.av_get_bits_per_sample(par.codec_id))
+
+		if name := C.av_get_sample_fmt_name(int32(par.format)); name != nil {
+			si.SampleFmt = C.GoString(name)
+		}
+
+		if layout := C.gmf_probe_channel_layout(par); layout != nil {
+			si.ChannelLayout = C.GoString(layout)
+			C.av_free(unsafe.Pointer(layout))
+		}
+	}
+
+	if this.avCtx.iformat != nil && this.avCtx.iformat.flags&C.AVFMT_SHOW_IDS != 0 {
+		si.Id = fmt.Sprintf("0x%x", int(st.avStream.id))
+	}
+
+	if start := int64(st.avStream.start_time); start != AV_NOPTS_VALUE {
+		si.StartPts = start
+		si.StartTime = newSeconds(float64(start) * tb.Av2qd())
+	}
+
+	if duration := int64(st.avStream.duration); duration != AV_NOPTS_VALUE {
+		si.DurationTs = duration
+		si.Duration = newSeconds(float64(duration) * tb.Av2qd())
+	}
+
+	for _, d := range dispositionNames {
+		if st.Disposition().Has(d.flag) {
+			si.Disposition[d.name] = 1
+		} else {
+			si.Disposition[d.name] = 0
+		}
+	}
+
+	for i := 0; i < int(C.gmf_probe_nb_side_data(st.avStream)); i++ {
+		sd := C.gmf_probe_side_data(st.avStream, C.int(i))
+
+		si.SideDataList = append(si.SideDataList, SideDataInfo{
+			SideDataType: C.GoString(C.av_packet_side_data_name(sd._type)),
+			Rotation:     int(C.gmf_probe_rotation(sd)),
+		})
+	}
+
+	return si
+}
+
+// fourccString formats codec tag the same way as av_fourcc_make_string.
+func fourccString(tag uint32) string {
+	var result string
+
+	for i := 0; i < 4; i++ {
+		c := tag & 0xff
+
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '.' || c == ' ' || c == '-' || c == '_' {
+			result += fmt.Sprintf("%c", c)
+		} else {
+			result += fmt.Sprintf("[%d]", c)
+		}
+
+		tag >>= 8
+	}
+
+	return result
+}
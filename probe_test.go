@@ -0,0 +1,74 @@
+package gmf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	info, err := Probe(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Format.NbStreams != 1 || len(info.Streams) != 1 {
+		t.Fatalf("Expected 1 stream, %d got\n", len(info.Streams))
+	}
+
+	st := info.Streams[0]
+
+	if st.CodecType != "video" || st.CodecName != "mpeg4" {
+		t.Fatalf("Expected video mpeg4 stream, %s %s got\n", st.CodecType, st.CodecName)
+	}
+
+	if st.Width != inputSampleWidth || st.Height != inputSampleHeight {
+		t.Fatalf("Expected dimension = %dx%d, %dx%d got\n", inputSampleWidth, inputSampleHeight, st.Width, st.Height)
+	}
+
+	if st.CodecTagString != "mp4v" {
+		t.Fatalf("Expected codec tag 'mp4v', '%s' got\n", st.CodecTagString)
+	}
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	// times are "%f" strings, zero start time is kept
+	seconds := regexp.MustCompile(`^[0-9]+\.[0-9]{6}$`)
+
+	format := result["format"].(map[string]interface{})
+	if d, ok := format["duration"].(string); !ok || !seconds.MatchString(d) {
+		t.Fatalf("Expected format duration as \"%%f\" string, %v got\n", format["duration"])
+	}
+
+	stream := result["streams"].([]interface{})[0].(map[string]interface{})
+	if stream["codec_type"] != "video" || stream["time_base"] == "" {
+		t.Fatalf("Unexpected stream json: %s\n", b)
+	}
+
+	if stream["start_time"] != "0.000000" {
+		t.Fatalf("Expected stream start_time \"0.000000\", %v got\n", stream["start_time"])
+	}
+
+	disposition := stream["disposition"].(map[string]interface{})
+	if len(disposition) != len(dispositionNames) || disposition["default"] != float64(1) {
+		t.Fatalf("Expected %d dispositions with default set, %v got\n", len(dispositionNames), disposition)
+	}
+
+	var decoded MediaInfo
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Format.Duration == nil || fmt.Sprintf("%f", *decoded.Format.Duration) != format["duration"] {
+		t.Fatalf("Expected duration %v decoded, %v got\n", format["duration"], decoded.Format.Duration)
+	}
+}
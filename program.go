@@ -0,0 +1,164 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat
+
+#include "libavformat/avformat.h"
+
+static AVProgram *gmf_program(AVFormatContext *ctx, int idx) {
+	return ctx->programs[idx];
+}
+
+static int gmf_program_stream_index(AVProgram *program, int idx) {
+	return (int)program->stream_index[idx];
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Program is AVProgram, e.g. MPEG-TS service, owned by the format context.
+type Program struct {
+	avProgram *C.AVProgram
+	avFmtCtx  *FmtCtx
+}
+
+// Id returns program id, for MPEG-TS it is the service id.
+func (p *Program) Id() int {
+	return int(p.avProgram.id)
+}
+
+// ProgramNum returns program number from the PAT.
+func (p *Program) ProgramNum() int {
+	return int(p.avProgram.program_num)
+}
+
+func (p *Program) PmtPid() int {
+	return int(p.avProgram.pmt_pid)
+}
+
+func (p *Program) PcrPid() int {
+	return int(p.avProgram.pcr_pid)
+}
+
+// Metadata returns a copy of the program metadata, e.g. service_name, service_provider.
+func (p *Program) Metadata() map[string]string {
+	return (&Dict{dict: p.avProgram.metadata}).Map()
+}
+
+// SetMetadata replaces the program metadata by m. mpegts muxer uses
+// service_name and service_provider for the SDT.
+func (p *Program) SetMetadata(m map[string]string) error {
+	return replaceDict(&p.avProgram.metadata, m)
+}
+
+// StreamIndices returns indices of the program streams.
+func (p *Program) StreamIndices() []int {
+	result := make([]int, 0, int(p.avProgram.nb_stream_indexes))
+
+	for i := 0; i < int(p.avProgram.nb_stream_indexes); i++ {
+		result = append(result, int(C.gmf_program_stream_index(p.avProgram, C.int(i))))
+	}
+
+	return result
+}
+
+// HasStream checks, if the stream belongs to the program.
+func (p *Program) HasStream(idx int) bool {
+	for _, i := range p.StreamIndices() {
+		if i == idx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddStream adds stream of the same format context to the program.
+func (p *Program) AddStream(idx int) error {
+	if idx < 0 || idx >= p.avFmtCtx.StreamsCnt() {
+		return errors.New(fmt.Sprintf("Stream index '%d' is out of range. There is only '%d' streams.", idx, p.avFmtCtx.StreamsCnt()))
+	}
+
+	C.av_program_add_stream_index(p.avFmtCtx.avCtx, p.avProgram.id, C.uint(idx))
+
+	return nil
+}
+
+func (this *FmtCtx) ProgramsCnt() int {
+	return int(this.avCtx.nb_programs)
+}
+
+// Programs returns all the programs of the context, e.g. services of the
+// multi program transport stream.
+func (this *FmtCtx) Programs() []*Program {
+	result := make([]*Program, 0, this.ProgramsCnt())
+
+	for i := 0; i < this.ProgramsCnt(); i++ {
+		result = append(result, &Program{avProgram: C.gmf_program(this.avCtx, C.int(i)), avFmtCtx: this})
+	}
+
+	return result
+}
+
+// GetProgram returns program by its id.
+func (this *FmtCtx) GetProgram(id int) (*Program, error) {
+	for _, p := range this.Programs() {
+		if p.Id() == id {
+			return p, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Program '%d' is not found", id))
+}
+
+// NewProgram creates program with the id on the output context, streams
+// are added with Program.AddStream. If program with the id already exists,
+// it is returned.
+func (this *FmtCtx) NewProgram(id int) (*Program, error) {
+	program := C.av_new_program(this.avCtx, C.int(id))
+	if program == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create program '%d'", id))
+	}
+
+	return &Program{avProgram: program, avFmtCtx: this}, nil
+}
+
+// SelectProgram marks all the streams and programs, except the program with the id,
+// as discarded, so the demuxer drops their packets (e.g. mpegts doesn't parse their PIDs).
+// It should be called right after the input is opened, packets already
+// buffered by the demuxer are returned anyway, unless the input is seeked.
+func (this *FmtCtx) SelectProgram(id int) error {
+	selected, err := this.GetProgram(id)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range this.Programs() {
+		if p.Id() == id {
+			p.avProgram.discard = C.AVDISCARD_DEFAULT
+		} else {
+			p.avProgram.discard = C.AVDISCARD_ALL
+		}
+	}
+
+	for i := 0; i < this.StreamsCnt(); i++ {
+		st, err := this.GetStream(i)
+		if err != nil {
+			return err
+		}
+
+		if selected.HasStream(i) {
+			st.SetDiscard(AVDISCARD_DEFAULT)
+		} else {
+			st.SetDiscard(AVDISCARD_ALL)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package gmf
+
+import (
+	"bytes"
+	"testing"
+)
+
+// writeTwoPrograms writes the sample video into two mpegts programs,
+// one stream per program.
+func writeTwoPrograms(t *testing.T) []byte {
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	for i := 0; i < 2; i++ {
+		ost := outputCtx.NewStream(nil)
+		if ost == nil {
+			t.Fatal("unable to create stream")
+		}
+
+		if err := ost.CopyCodecPar(ist.GetCodecPar()); err != nil {
+			t.Fatal(err)
+		}
+		ost.avStream.codecpar.codec_tag = 0
+		ost.SetTimeBase(ist.TimeBase().AVR())
+
+		program, err := outputCtx.NewProgram(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := program.AddStream(ost.Index()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := program.SetMetadata(map[string]string{"service_name": "service", "service_provider": "gmf"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := outputCtx.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		pkt := it.Packet()
+
+		for i := 0; i < 2; i++ {
+			ost := assert(outputCtx.GetStream(i)).(*Stream)
+
+			p := pkt.Clone()
+			RescaleTs(p, ist.TimeBase(), ost.TimeBase())
+			p.SetStreamIndex(i)
+
+			err := outputCtx.WritePacket(p)
+			p.Free()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pkt.Free()
+	}
+
+	if err := outputCtx.WriteTrailer(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestPrograms(t *testing.T) {
+	data := writeTwoPrograms(t)
+
+	inputCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	if inputCtx.ProgramsCnt() != 2 {
+		t.Fatalf("Expected 2 programs, %d got\n", inputCtx.ProgramsCnt())
+	}
+
+	for _, p := range inputCtx.Programs() {
+		if indices := p.StreamIndices(); len(indices) != 1 {
+			t.Fatalf("Expected one stream in program %d, %v got\n", p.Id(), indices)
+		}
+
+		if name := p.Metadata()["service_name"]; name != "service" {
+			t.Fatalf("Expected service name 'service', '%s' got\n", name)
+		}
+	}
+
+	program, err := inputCtx.GetProgram(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inputCtx.SelectProgram(program.Id()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inputCtx.GetProgram(3); err == nil {
+		t.Fatalf("Expected error getting not existing program\n")
+	}
+
+	// drop packets buffered while the input was probed
+	ist := assert(inputCtx.GetStream(program.StreamIndices()[0])).(*Stream)
+	if err := inputCtx.SeekFile(ist, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	it := inputCtx.Packets(0)
+	defer it.Close()
+
+	cnt := 0
+
+	for it.Next() {
+		pkt := it.Packet()
+		if !program.HasStream(pkt.StreamIndex()) {
+			t.Fatalf("Unexpected packet of stream %d outside of program %d\n", pkt.StreamIndex(), program.Id())
+		}
+		pkt.Free()
+		cnt++
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cnt == 0 {
+		t.Fatalf("Expected packets of program %d\n", program.Id())
+	}
+}
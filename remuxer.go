@@ -0,0 +1,515 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavformat libavcodec
+
+#include <string.h>
+
+#include "libavformat/avformat.h"
+#include "libavcodec/avcodec.h"
+
+static int gmf_remux_is_format(const char *name, const char *list) {
+	size_t len = strlen(name);
+	const char *p = list;
+
+	while ((p = strstr(p, name)) != NULL) {
+		if ((p == list || p[-1] == ',') && (p[len] == ',' || p[len] == '\0')) {
+			return 1;
+		}
+		p += len;
+	}
+
+	return 0;
+}
+
+// gmf_remux_bsf returns bitstream filter required to copy the stream from ifmt to ofmt, or NULL.
+static const char *gmf_remux_bsf(const AVCodecParameters *par, const char *ifmt, const char *ofmt) {
+	int annexb = gmf_remux_is_format(ofmt, "mpegts,h264,hevc");
+	int avcc   = par->extradata_size > 0 && par->extradata[0] == 1;
+
+	if (annexb && avcc && par->codec_id == AV_CODEC_ID_H264) {
+		return "h264_mp4toannexb";
+	}
+
+	if (annexb && avcc && par->codec_id == AV_CODEC_ID_HEVC) {
+		return "hevc_mp4toannexb";
+	}
+
+	if (par->codec_id == AV_CODEC_ID_AAC && gmf_remux_is_format(ifmt, "mpegts,aac")
+		&& gmf_remux_is_format(ofmt, "mp4,mov,ipod,3gp,3g2,ismv,f4v,flv,matroska")) {
+		return "aac_adtstoasc";
+	}
+
+	return NULL;
+}
+
+static const char *gmf_remux_input_name(AVFormatContext *ctx) {
+	return ctx->iformat ? ctx->iformat->name : "";
+}
+
+static const char *gmf_remux_output_name(AVFormatContext *ctx) {
+	return ctx->oformat ? ctx->oformat->name : "";
+}
+
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type RemuxerConfig struct {
+	// Streams are indices of the input streams to copy. If empty, all the audio,
+	// video and subtitle streams are copied. Inputs are concatenated, so streams
+	// of every input must have the same indices and codecs.
+	Streams []int
+	// Start and End trim the output, zero means no trimming. The output starts
+	// at the last keyframe before Start, video ends before the first keyframe
+	// after End. Timestamps are shifted, so the output starts at Start.
+	Start time.Duration
+	End   time.Duration
+	// BitstreamFilters by the input stream index, e.g. {0: {"h264_metadata"}}, are
+	// applied after the filters required by the output format.
+	BitstreamFilters map[int][]string
+}
+
+type remuxStream struct {
+	index      int
+	ost        *Stream
+	video      bool
+	bsfs       []*BitstreamFilter
+	inTimeBase AVRational
+	started    bool
+	done       bool
+	// packets since the last keyframe before Start
+	pending []*Packet
+	lastDts int64
+}
+
+// outTimeBase returns time base of the packets passed through bitstream filters.
+func (st *remuxStream) outTimeBase() AVRational {
+	if n := len(st.bsfs); n > 0 {
+		return st.bsfs[n-1].OutputTimeBase()
+	}
+
+	return st.inTimeBase
+}
+
+// Remuxer copies streams of one or more inputs into the output without decoding.
+// Inputs are concatenated, timestamps of every next input are offset by the end
+// of the previous one. Bitstream filters required by the output format
+// (e.g. h264_mp4toannexb for MPEG-TS) are inserted automatically, DTS of the
+// output is repaired to be monotonically increasing.
+//
+// Input and output contexts are owned by caller, the output header and trailer are
+// written by the remuxer. Extradata of the first input is used for the output,
+// so concatenated inputs should be encoded with the same settings.
+type Remuxer struct {
+	inputs  []*FmtCtx
+	output  *FmtCtx
+	streams map[int]*remuxStream
+	order   []*remuxStream
+	start   int64
+	end     int64
+	// output time of the current input start, in AV_TIME_BASE
+	offset int64
+}
+
+func NewRemuxer(inputs []*FmtCtx, output *FmtCtx, config RemuxerConfig) (*Remuxer, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("No inputs to remux")
+	}
+
+	if config.End > 0 && config.End <= config.Start {
+		return nil, errors.New(fmt.Sprintf("Invalid trim range %s - %s", config.Start, config.End))
+	}
+
+	r := &Remuxer{
+		inputs:  inputs,
+		output:  output,
+		streams: make(map[int]*remuxStream),
+		order:   make([]*remuxStream, 0),
+		start:   int64(config.Start / time.Microsecond),
+		end:     int64(config.End / time.Microsecond),
+	}
+
+	r.offset = -r.start
+
+	indices := config.Streams
+
+	if len(indices) == 0 {
+		for i := 0; i < inputs[0].StreamsCnt(); i++ {
+			if ist, err := inputs[0].GetStream(i); err == nil && (ist.IsVideo() || ist.IsAudio() || ist.IsSubtitle()) {
+				indices = append(indices, i)
+			}
+		}
+	}
+
+	if len(indices) == 0 {
+		return nil, errors.New("No streams to remux")
+	}
+
+	for _, idx := range indices {
+		if _, ok := r.streams[idx]; ok {
+			r.Free()
+			return nil, errors.New(fmt.Sprintf("Input stream %d is mapped twice", idx))
+		}
+
+		st, err := r.addStream(idx, config.BitstreamFilters[idx])
+		if st != nil {
+			r.streams[idx] = st
+			r.order = append(r.order, st)
+		}
+		if err != nil {
+			r.Free()
+			return nil, err
+		}
+	}
+
+	if err := r.checkInputs(); err != nil {
+		r.Free()
+		return nil, err
+	}
+
+	// negative timestamps, e.g. of B-frames or of the keyframe before Start, are shifted by the muxer
+	if r.output.avCtx.avoid_negative_ts == C.AVFMT_AVOID_NEG_TS_AUTO {
+		r.output.avCtx.avoid_negative_ts = C.AVFMT_AVOID_NEG_TS_MAKE_NON_NEGATIVE
+	}
+
+	return r, nil
+}
+
+func (r *Remuxer) addStream(idx int, filters []string) (*remuxStream, error) {
+	ist, err := r.inputs[0].GetStream(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &remuxStream{
+		index:      idx,
+		video:      ist.IsVideo() && !ist.IsAttachedPic(),
+		inTimeBase: ist.TimeBase(),
+		lastDts:    AV_NOPTS_VALUE,
+	}
+
+	if st.ost = r.output.NewStream(nil); st.ost == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create output stream for input stream %d", idx))
+	}
+
+	cp := ist.GetCodecPar()
+
+	if name := C.gmf_remux_bsf(cp.avCodecParameters, C.gmf_remux_input_name(r.inputs[0].avCtx), C.gmf_remux_output_name(r.output.avCtx)); name != nil {
+		filters = append([]string{C.GoString(name)}, filters...)
+	}
+
+	tb := st.inTimeBase.AVR()
+
+	for _, name := range filters {
+		bsf, err := NewBitstreamFilter(name, cp, tb)
+		if err != nil {
+			return st, err
+		}
+
+		st.bsfs = append(st.bsfs, bsf)
+		cp, tb = bsf.OutputCodecParameters(), bsf.OutputTimeBase().AVR()
+	}
+
+	if err := st.ost.CopyCodecPar(cp); err != nil {
+		return st, err
+	}
+
+	st.ost.avStream.codecpar.codec_tag = 0
+	st.ost.SetTimeBase(tb)
+	st.ost.SetDisposition(ist.Disposition())
+
+	return st, st.ost.CopyMetadata(ist)
+}
+
+// checkInputs checks that all the inputs have the mapped streams of the same codecs.
+func (r *Remuxer) checkInputs() error {
+	first := r.inputs[0]
+
+	for i, input := range r.inputs[1:] {
+		for _, st := range r.order {
+			ist, err := input.GetStream(st.index)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Input %d has no stream %d", i+1, st.index))
+			}
+
+			src, _ := first.GetStream(st.index)
+
+			if ist.Type() != src.Type() || ist.avStream.codecpar.codec_id != src.avStream.codecpar.codec_id {
+				return errors.New(fmt.Sprintf("Stream %d of input %d doesn't match the first input", st.index, i+1))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run remuxes all the inputs up to the end, or up to End.
+func (r *Remuxer) Run() error {
+	return r.RunWithContext(context.Background())
+}
+
+// RunWithContext is the same as Run, it returns ctx.Err() as soon as ctx is done.
+// The output trailer isn't written in this case.
+func (r *Remuxer) RunWithContext(ctx context.Context) error {
+	if err := r.output.WriteHeader(); err != nil {
+		return err
+	}
+
+	for i, input := range r.inputs {
+		if r.isDone() {
+			break
+		}
+
+		if err := r.remuxInput(ctx, i, input); err != nil {
+			return err
+		}
+	}
+
+	return r.flush()
+}
+
+func (r *Remuxer) isDone() bool {
+	for _, st := range r.order {
+		if !st.done {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Remuxer) remuxInput(ctx context.Context, idx int, input *FmtCtx) error {
+	startTime := int64(input.StartTime())
+	if startTime == AV_NOPTS_VALUE {
+		startTime = 0
+	}
+
+	// only the first input is seeked, the next ones start right after it
+	if idx == 0 && r.start > 0 {
+		target := C.int64_t(startTime + r.start)
+
+		// not seekable input is read from the start, packets before Start are dropped by trimPacket
+		if averr := C.avformat_seek_file(input.avCtx, -1, C.INT64_MIN, target, target, 0); averr < 0 && input.avCtx.pb != nil && input.avCtx.pb.seekable != 0 {
+			return newAVError(int(averr), "Unable to seek to the trim start of", input.filename)
+		}
+	}
+
+	end := r.offset
+	shifts := make(map[int]int64)
+
+	for {
+		pkt, err := input.ReadPacket(ctx)
+		if isEOF(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		st, ok := r.streams[pkt.StreamIndex()]
+		if !ok || st.done {
+			pkt.Free()
+			continue
+		}
+
+		ist, _ := input.GetStream(st.index)
+		tb := ist.TimeBase()
+
+		shift, ok := shifts[st.index]
+		if !ok {
+			shift = RescaleQ(r.offset-startTime, AV_TIME_BASE_Q, tb)
+			shifts[st.index] = shift
+		}
+
+		if pkt.Pts() != AV_NOPTS_VALUE {
+			pkt.SetPts(pkt.Pts() + shift)
+		}
+		if pkt.Dts() != AV_NOPTS_VALUE {
+			pkt.SetDts(pkt.Dts() + shift)
+		}
+
+		ts := pkt.Pts()
+		if ts == AV_NOPTS_VALUE {
+			ts = pkt.Dts()
+		}
+		if ts == AV_NOPTS_VALUE {
+			pkt.Free()
+			continue
+		}
+
+		// output time of the packet
+		t := RescaleQ(ts, tb, AV_TIME_BASE_Q)
+
+		if pktEnd := t + RescaleQ(pkt.Duration(), tb, AV_TIME_BASE_Q); pktEnd > end {
+			end = pktEnd
+		}
+
+		RescaleTs(pkt, tb, st.inTimeBase)
+		pkt.avPacket.pos = -1
+
+		if err := r.trimPacket(st, pkt, t); err != nil {
+			return err
+		}
+
+		if r.isDone() {
+			break
+		}
+	}
+
+	r.offset = end
+
+	return nil
+}
+
+// trimPacket writes the packet, if it's within the trim range, t is the output time of the packet.
+func (r *Remuxer) trimPacket(st *remuxStream, pkt *Packet, t int64) error {
+	if r.end > 0 && t >= r.end-r.start && (!st.video || pkt.IsKey()) {
+		st.done = true
+		pkt.Free()
+		return nil
+	}
+
+	if st.started {
+		return r.writePacket(st, pkt)
+	}
+
+	if t < 0 {
+		// the last GOP before Start is kept
+		if pkt.IsKey() {
+			freePackets(st.pending)
+			st.pending = []*Packet{pkt}
+		} else if len(st.pending) > 0 {
+			st.pending = append(st.pending, pkt)
+		} else {
+			pkt.Free()
+		}
+
+		return nil
+	}
+
+	if pkt.IsKey() && t == 0 {
+		// the keyframe is exactly at Start
+		freePackets(st.pending)
+		st.pending = nil
+	}
+
+	if len(st.pending) == 0 && !pkt.IsKey() {
+		// the stream can't start without a keyframe
+		pkt.Free()
+		return nil
+	}
+
+	st.started = true
+
+	pending := append(st.pending, pkt)
+	st.pending = nil
+
+	for i, p := range pending {
+		if err := r.writePacket(st, p); err != nil {
+			freePackets(pending[i+1:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePacket filters the packet and writes the result, pkt is freed.
+func (r *Remuxer) writePacket(st *remuxStream, pkt *Packet) error {
+	pkts, err := filterPackets(st.bsfs, pkt)
+	if err != nil {
+		return err
+	}
+
+	return r.writeFiltered(st, pkts)
+}
+
+// writeFiltered repairs timestamps of the filtered packets and writes them, pkts are freed.
+func (r *Remuxer) writeFiltered(st *remuxStream, pkts []*Packet) error {
+	for i, p := range pkts {
+		RescaleTs(p, st.outTimeBase(), st.ost.TimeBase())
+		r.repairTimestamps(st, p)
+		p.SetStreamIndex(st.ost.Index())
+
+		err := r.output.WritePacket(p)
+		p.Free()
+		if err != nil {
+			freePackets(pkts[i+1:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repairTimestamps makes DTS monotonically increasing and not greater than PTS, like ffmpeg does.
+func (r *Remuxer) repairTimestamps(st *remuxStream, pkt *Packet) {
+	pts, dts := pkt.Pts(), pkt.Dts()
+
+	if dts == AV_NOPTS_VALUE {
+		dts = pts
+	}
+
+	if st.lastDts != AV_NOPTS_VALUE {
+		next := st.lastDts + 1
+		if r.output.avCtx.oformat.flags&C.AVFMT_TS_NONSTRICT != 0 {
+			next = st.lastDts
+		}
+
+		if dts == AV_NOPTS_VALUE || dts < next {
+			if pts != AV_NOPTS_VALUE && pts < next {
+				pts = next
+			}
+			dts = next
+		}
+	}
+
+	if pts != AV_NOPTS_VALUE && dts != AV_NOPTS_VALUE && pts < dts {
+		pts = dts
+	}
+
+	pkt.SetPts(pts)
+	pkt.SetDts(dts)
+
+	st.lastDts = dts
+}
+
+// flush drains bitstream filters and writes the trailer.
+func (r *Remuxer) flush() error {
+	for _, st := range r.order {
+		freePackets(st.pending)
+		st.pending = nil
+
+		pkts, err := filterPackets(st.bsfs, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := r.writeFiltered(st, pkts); err != nil {
+			return err
+		}
+	}
+
+	return r.output.WriteTrailer()
+}
+
+// Free releases bitstream filters, input and output contexts should be freed by caller.
+func (r *Remuxer) Free() {
+	for _, st := range r.order {
+		freePackets(st.pending)
+		st.pending = nil
+
+		for _, bsf := range st.bsfs {
+			bsf.Free()
+		}
+		st.bsfs = nil
+	}
+}
@@ -0,0 +1,328 @@
+package gmf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func remux(t *testing.T, filenames []string, format string, config RemuxerConfig) *FmtCtx {
+	inputs := make([]*FmtCtx, 0, len(filenames))
+
+	for _, filename := range filenames {
+		inputCtx, err := NewInputCtx(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer inputCtx.Free()
+
+		inputs = append(inputs, inputCtx)
+	}
+
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRemuxer(inputs, outputCtx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Run()
+	r.Free()
+	outputCtx.Free()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resultCtx
+}
+
+// readTimestamps returns dts of the stream 0 packets, checking they are monotonic.
+func readTimestamps(t *testing.T, ctx *FmtCtx) ([]int64, []bool) {
+	dts := make([]int64, 0)
+	keys := make([]bool, 0)
+
+	it := ctx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		pkt := it.Packet()
+
+		if n := len(dts); n > 0 && pkt.Dts() <= dts[n-1] {
+			t.Fatalf("Expected monotonic dts, %d after %d got\n", pkt.Dts(), dts[n-1])
+		}
+
+		dts = append(dts, pkt.Dts())
+		keys = append(keys, pkt.IsKey())
+		pkt.Free()
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return dts, keys
+}
+
+func TestRemuxer(t *testing.T) {
+	resultCtx := remux(t, []string{inputSampleFilename}, "mpegts", RemuxerConfig{})
+	defer resultCtx.Free()
+
+	if cnt := countPackets(t, resultCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestRemuxerConcat(t *testing.T) {
+	resultCtx := remux(t, []string{inputSampleFilename, inputSampleFilename}, "matroska", RemuxerConfig{})
+	defer resultCtx.Free()
+
+	dts, _ := readTimestamps(t, resultCtx)
+	if len(dts) != 50 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 50, len(dts))
+	}
+
+	// the second input follows the first one without a gap
+	if step, gap := dts[1]-dts[0], dts[25]-dts[24]; gap < step/2 || gap > step*2 {
+		t.Fatalf("Expected the second input right after the first one, gap %d, frame %d got\n", gap, step)
+	}
+}
+
+func TestRemuxerTrim(t *testing.T) {
+	f, err := ioutil.TempFile("", "gmf-trim-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	writeSyntheticClip(t, f.Name(), 100)
+
+	// expected range: from the last keyframe at or before frame 25 (1s)
+	// up to the first keyframe at or after frame 50 (2s)
+	inputCtx, err := NewInputCtx(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, keys := readTimestamps(t, inputCtx)
+	inputCtx.Free()
+
+	first, last := 0, len(keys)
+	for i, key := range keys {
+		if key && i <= 25 {
+			first = i
+		}
+		if key && i >= 50 && last == len(keys) {
+			last = i
+		}
+	}
+
+	resultCtx := remux(t, []string{f.Name()}, "matroska", RemuxerConfig{
+		Start: time.Second,
+		End:   2 * time.Second,
+	})
+	defer resultCtx.Free()
+
+	dts, resultKeys := readTimestamps(t, resultCtx)
+
+	if len(dts) != last-first {
+		t.Fatalf("Expected %d packets of frames %d - %d, %d got\n", last-first, first, last, len(dts))
+	}
+
+	if !resultKeys[0] {
+		t.Fatalf("Expected the output to start with a keyframe\n")
+	}
+}
+
+// transcodeToFile transcodes the input to a temporary file of the extension,
+// the file must be removed by caller.
+func transcodeToFile(t *testing.T, inputCtx *FmtCtx, ext string, config TranscoderConfig) string {
+	f, err := ioutil.TempFile("", "gmf-remux-*."+ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	outputCtx, err := NewOutputCtx(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+
+	tr, err := NewTranscoder(inputCtx, outputCtx, config)
+	if err == nil {
+		err = tr.Run()
+		tr.Free()
+	}
+	outputCtx.Free()
+
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+
+	return f.Name()
+}
+
+// remuxFilters returns bitstream filters inserted by the remuxer of the file to the format.
+func remuxFilters(t *testing.T, filename string, format string) ([]string, *FmtCtx) {
+	inputCtx, err := NewInputCtx(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRemuxer([]*FmtCtx{inputCtx}, outputCtx, RemuxerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0)
+	for _, bsf := range r.order[0].bsfs {
+		names = append(names, bsf.Name())
+	}
+
+	err = r.Run()
+	r.Free()
+	outputCtx.Free()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return names, resultCtx
+}
+
+func TestRemuxerH264ToAnnexB(t *testing.T) {
+	if _, err := FindEncoder("libx264"); err != nil {
+		t.Skip("libx264 isn't available")
+	}
+
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	filename := transcodeToFile(t, inputCtx, "mp4", TranscoderConfig{
+		Streams: []TranscodeStream{{InputIndex: 0, Codec: "libx264"}},
+	})
+	defer os.Remove(filename)
+
+	names, resultCtx := remuxFilters(t, filename, "mpegts")
+	defer resultCtx.Free()
+
+	if len(names) != 1 || names[0] != "h264_mp4toannexb" {
+		t.Fatalf("Expected h264_mp4toannexb inserted, %v got\n", names)
+	}
+
+	pkt, err := resultCtx.GetNextPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkt.Free()
+
+	if data := pkt.Data(); !bytes.HasPrefix(data, []byte{0, 0, 0, 1}) && !bytes.HasPrefix(data, []byte{0, 0, 1}) {
+		t.Fatalf("Expected Annex B start code, % x got\n", data[:4])
+	}
+}
+
+func TestRemuxerAdtsToMp4(t *testing.T) {
+	inputCtx, err := NewInputCtxWithFormatName("sine=frequency=1000:duration=1", "lavfi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	filename := transcodeToFile(t, inputCtx, "aac", TranscoderConfig{
+		Streams: []TranscodeStream{{InputIndex: 0, Codec: "aac"}},
+	})
+	defer os.Remove(filename)
+
+	names, resultCtx := remuxFilters(t, filename, "mp4")
+	defer resultCtx.Free()
+
+	if len(names) != 1 || names[0] != "aac_adtstoasc" {
+		t.Fatalf("Expected aac_adtstoasc inserted, %v got\n", names)
+	}
+
+	pkt, err := resultCtx.GetNextPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkt.Free()
+
+	// raw AAC, no ADTS header syncword
+	if data := pkt.Data(); len(data) > 1 && data[0] == 0xff && data[1]&0xf0 == 0xf0 {
+		t.Fatalf("Expected ADTS header stripped, % x got\n", data[:2])
+	}
+}
+
+func TestRemuxerRepairTimestamps(t *testing.T) {
+	outputCtx, err := NewOutputCtxToWriter(new(bytes.Buffer), "mpegts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	r := &Remuxer{output: outputCtx}
+	st := &remuxStream{lastDts: AV_NOPTS_VALUE}
+
+	// pts, dts pairs: negative start, going back, repeated and missing dts
+	input := [][2]int64{{-5, -10}, {10, 0}, {20, -3}, {30, -3}, {15, AV_NOPTS_VALUE}, {60, 50}}
+
+	prev := AV_NOPTS_VALUE
+
+	for i, ts := range input {
+		pkt := NewPacket()
+		pkt.SetPts(ts[0])
+		pkt.SetDts(ts[1])
+
+		r.repairTimestamps(st, pkt)
+		pts, dts := pkt.Pts(), pkt.Dts()
+		pkt.Free()
+
+		if i == 0 && (pts != -5 || dts != -10) {
+			t.Fatalf("Expected negative first timestamps kept, pts %d, dts %d got\n", pts, dts)
+		}
+
+		if dts == AV_NOPTS_VALUE || (prev != AV_NOPTS_VALUE && dts < prev) {
+			t.Fatalf("Expected monotonic dts of packet %d, %d after %d got\n", i, dts, prev)
+		}
+
+		if pts < dts {
+			t.Fatalf("Expected pts not less than dts of packet %d, pts %d, dts %d got\n", i, pts, dts)
+		}
+
+		// monotonic timestamps aren't changed
+		if ts[1] != AV_NOPTS_VALUE && ts[1] > prev && (pts != ts[0] || dts != ts[1]) {
+			t.Fatalf("Expected packet %d unchanged, pts %d, dts %d got\n", i, pts, dts)
+		}
+
+		prev = dts
+	}
+}
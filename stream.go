@@ -22,11 +22,14 @@ type Stream struct {
 	SwrCtx   *SwrCtx
 	AvFifo   *AVAudioFifo
 	cc       *CodecCtx
+	bsfs     []*BitstreamFilter
 	Pts      int64
 	CgoMemoryManage
 }
 
 func (s *Stream) Free() {
+	s.freeBitstreamFilters()
+
 	if s.SwsCtx != nil {
 		s.SwsCtx.Free()
 	}
@@ -45,8 +48,12 @@ func (s *Stream) DumpContexCodec(codec *CodecCtx) {
 	}
 }
 
+// SetCodecFlags sets global header flag on the stream codec context, if any.
+// It takes effect only if called before the codec context is opened.
 func (s *Stream) SetCodecFlags() {
-	s.avStream.codec.flags |= C.AV_CODEC_FLAG_GLOBAL_HEADER
+	if s.cc != nil {
+		s.cc.avCodecCtx.flags |= C.AV_CODEC_FLAG_GLOBAL_HEADER
+	}
 }
 
 func (s *Stream) CodecCtx() *CodecCtx {
@@ -65,7 +72,7 @@ func (s *Stream) CodecCtxWithCuda(withCuda bool) *CodecCtx {
 	)
 
 	// Open input codec context
-	c, err = FindDecoder(int(s.avStream.codec.codec_id))
+	c, err = FindDecoder(int(s.avStream.codecpar.codec_id))
 	if err != nil {
 		return nil
 	}
@@ -88,11 +95,13 @@ func (s *Stream) CodecCtxWithCuda(withCuda bool) *CodecCtx {
 		panic("error copying parameters to codec context")
 	}
 
+	s.cc.avCodecCtx.pkt_timebase = s.avStream.time_base
+
 	if err := s.cc.Open(nil); err != nil {
 		panic("error opening codec context")
 	}
 
-	s.cc.avCodecCtx.time_base = s.avStream.codec.time_base
+	s.cc.avCodecCtx.time_base = s.avStream.time_base
 
 	return s.cc
 }
@@ -135,7 +144,7 @@ func (s *Stream) TimeBase() AVRational {
 }
 
 func (s *Stream) Type() int32 {
-	return s.CodecCtx().Type()
+	return int32(s.avStream.codecpar.codec_type)
 }
 
 func (s *Stream) IsAudio() bool {
@@ -146,10 +155,30 @@ func (s *Stream) IsVideo() bool {
 	return (s.Type() == AVMEDIA_TYPE_VIDEO)
 }
 
+func (s *Stream) IsSubtitle() bool {
+	return (s.Type() == AVMEDIA_TYPE_SUBTITLE)
+}
+
 func (s *Stream) Duration() int64 {
 	return int64(s.avStream.duration)
 }
 
+// Metadata returns a copy of the stream metadata, e.g. language, title, handler_name.
+func (s *Stream) Metadata() map[string]string {
+	return (&Dict{dict: s.avStream.metadata}).Map()
+}
+
+// SetMetadata replaces the stream metadata by m, e.g. {"language": "eng"}
+// for the audio track. It should be called before the header is written.
+func (s *Stream) SetMetadata(m map[string]string) error {
+	return replaceDict(&s.avStream.metadata, m)
+}
+
+// CopyMetadata merges metadata of the src stream into this stream metadata.
+func (s *Stream) CopyMetadata(src *Stream) error {
+	return (&Dict{dict: src.avStream.metadata}).copyTo(&s.avStream.metadata)
+}
+
 func (s *Stream) SetTimeBase(val AVR) *Stream {
 	s.avStream.time_base.num = C.int(val.Num)
 	s.avStream.time_base.den = C.int(val.Den)
@@ -193,3 +222,91 @@ func (s *Stream) CopyCodecPar(cp *CodecParameters) error {
 
 	return nil
 }
+
+// AddBitstreamFilter appends a bitstream filter to the output stream chain.
+// Packets written by FmtCtx.WritePacket are passed through the chain.
+// It must be called after stream codec parameters are set and before WriteHeader.
+// The chain is re-initialized by WriteHeader, if the muxer changes the stream
+// time base, e.g. mpegts sets 1/90000.
+func (s *Stream) AddBitstreamFilter(name string, options ...*Option) error {
+	cp, tb := s.GetCodecPar(), s.TimeBase()
+
+	if n := len(s.bsfs); n > 0 {
+		cp, tb = s.bsfs[n-1].OutputCodecParameters(), s.bsfs[n-1].OutputTimeBase()
+	}
+
+	bsf, err := NewBitstreamFilter(name, cp, tb.AVR(), options...)
+	if err != nil {
+		return err
+	}
+
+	if err := s.CopyCodecPar(bsf.OutputCodecParameters()); err != nil {
+		bsf.Free()
+		return err
+	}
+
+	s.SetTimeBase(bsf.OutputTimeBase().AVR())
+	s.bsfs = append(s.bsfs, bsf)
+
+	return nil
+}
+
+// resetBitstreamFilters re-initializes the chain with the current stream
+// time base, the filters are created again with the same options.
+func (s *Stream) resetBitstreamFilters() error {
+	if len(s.bsfs) == 0 || s.bsfs[0].InputTimeBase().AVR() == s.TimeBase().AVR() {
+		return nil
+	}
+
+	cp, tb := s.bsfs[0].InputCodecParameters(), s.TimeBase()
+	bsfs := make([]*BitstreamFilter, 0, len(s.bsfs))
+
+	for _, prev := range s.bsfs {
+		bsf, err := NewBitstreamFilter(prev.Name(), cp, tb.AVR(), prev.options...)
+		if err != nil {
+			for _, b := range bsfs {
+				b.Free()
+			}
+			return err
+		}
+
+		bsfs = append(bsfs, bsf)
+		cp, tb = bsf.OutputCodecParameters(), bsf.OutputTimeBase()
+	}
+
+	s.freeBitstreamFilters()
+	s.bsfs = bsfs
+
+	return nil
+}
+
+func (s *Stream) freeBitstreamFilters() {
+	for _, bsf := range s.bsfs {
+		bsf.Free()
+	}
+
+	s.bsfs = nil
+}
+
+// filterPacket passes the packet through the stream bitstream filters.
+// Nil packet drains the chain.
+func (s *Stream) filterPacket(p *Packet) ([]*Packet, error) {
+	pkts, err := filterPackets(s.bsfs, p)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkt := range pkts {
+		pkt.SetStreamIndex(s.Index())
+	}
+
+	return pkts, nil
+}
+
+func freePackets(pkts []*Packet) {
+	for _, pkt := range pkts {
+		if pkt != nil {
+			pkt.Free()
+		}
+	}
+}
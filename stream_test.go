@@ -1,6 +1,7 @@
 package gmf
 
 import (
+	"bytes"
 	"log"
 	"testing"
 )
@@ -63,3 +64,69 @@ func TestStreamInputCtx(t *testing.T) {
 
 	inputCtx.Free()
 }
+
+func TestStreamDisposition(t *testing.T) {
+	d := AV_DISPOSITION_DEFAULT | AV_DISPOSITION_FORCED
+
+	if !d.Has(AV_DISPOSITION_FORCED) || d.Has(AV_DISPOSITION_DEFAULT|AV_DISPOSITION_DUB) || d.String() != "default+forced" {
+		t.Fatalf("Unexpected disposition flags %s\n", d)
+	}
+
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := func(inputCtx *FmtCtx, ist, ost *Stream) {
+		ost.SetDisposition(AV_DISPOSITION_DEFAULT).AddDisposition(AV_DISPOSITION_FORCED | AV_DISPOSITION_COMMENT)
+		ost.RemoveDisposition(AV_DISPOSITION_COMMENT)
+
+		if ost.Disposition() != d {
+			t.Fatalf("Expected disposition %s, %s got\n", d, ost.Disposition())
+		}
+	}
+
+	if err := remuxSampleWith(t, outputCtx, prepare); err != nil {
+		t.Fatal(err)
+	}
+	outputCtx.Free()
+
+	inputCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	if !ist.IsDefault() || !ist.Disposition().Has(AV_DISPOSITION_FORCED) || ist.IsAttachedPic() {
+		t.Fatalf("Expected default forced stream, %s got\n", ist.Disposition())
+	}
+}
+
+func TestStreamDiscard(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+
+	if ist.Discard() != AVDISCARD_DEFAULT {
+		t.Fatalf("Expected default discard level, %d got\n", ist.Discard())
+	}
+
+	ist.SetDiscard(AVDISCARD_ALL)
+
+	// drop packets buffered while the input was probed
+	if err := inputCtx.SeekFile(ist, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if cnt := countPackets(t, inputCtx); cnt != 0 {
+		t.Fatalf("Expected no packets of discarded stream, %d got\n", cnt)
+	}
+}
@@ -0,0 +1,384 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavcodec libavutil
+
+#include <stdlib.h>
+#include <string.h>
+#include "libavcodec/avcodec.h"
+#include "libavutil/mem.h"
+#include "libavutil/pixfmt.h"
+
+static AVSubtitleRect *gmf_sub_rect(AVSubtitle *sub, int idx) {
+	return sub->rects[idx];
+}
+
+static uint32_t gmf_sub_rect_color(AVSubtitleRect *r, int idx) {
+	return ((uint32_t *)r->data[1])[idx];
+}
+
+static int gmf_sub_alloc_rects(AVSubtitle *sub, int n) {
+	int i;
+
+	if (!(sub->rects = av_calloc(n, sizeof(*sub->rects)))) {
+		return AVERROR(ENOMEM);
+	}
+
+	for (i = 0; i < n; i++) {
+		if (!(sub->rects[i] = av_mallocz(sizeof(AVSubtitleRect)))) {
+			return AVERROR(ENOMEM);
+		}
+		sub->num_rects++;
+	}
+
+	return 0;
+}
+
+// text and ass are copied by av_strdup, as avsubtitle_free releases them by av_free.
+static int gmf_sub_rect_text(AVSubtitleRect *r, const char *text, const char *ass) {
+	if (text && !(r->text = av_strdup(text))) {
+		return AVERROR(ENOMEM);
+	}
+
+	if (ass && !(r->ass = av_strdup(ass))) {
+		return AVERROR(ENOMEM);
+	}
+
+	return 0;
+}
+
+static int gmf_sub_rect_bitmap(AVSubtitleRect *r, const uint8_t *bitmap, const uint32_t *palette, int nb_colors) {
+	int size = r->w * r->h;
+
+	// palette is always AVPALETTE_SIZE, as decoders allocate it
+	if (!(r->data[0] = av_malloc(size > 0 ? size : 1)) || !(r->data[1] = av_mallocz(AVPALETTE_SIZE))) {
+		return AVERROR(ENOMEM);
+	}
+
+	if (size > 0) {
+		memcpy(r->data[0], bitmap, size);
+	}
+
+	if (nb_colors > 0) {
+		memcpy(r->data[1], palette, nb_colors * sizeof(*palette));
+	}
+
+	r->linesize[0] = r->w;
+	r->nb_colors = nb_colors;
+
+	return 0;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+const (
+	SUBTITLE_NONE   int = C.SUBTITLE_NONE
+	SUBTITLE_BITMAP int = C.SUBTITLE_BITMAP
+	SUBTITLE_TEXT   int = C.SUBTITLE_TEXT
+	SUBTITLE_ASS    int = C.SUBTITLE_ASS
+)
+
+const (
+	AV_SUBTITLE_FLAG_FORCED int = C.AV_SUBTITLE_FLAG_FORCED
+)
+
+// maximal size of the encoded subtitle, the same as ffmpeg tool uses
+const subtitleMaxSize = 1 << 20
+
+// SubtitleRect is a region of the subtitle. Depending on Type it is either
+// a paletted bitmap, a plain text, or an ASS dialogue event, e.g.
+// "0,0,Default,,0,0,0,,Hello {\i1}world{\i0}", as text decoders produce.
+type SubtitleRect struct {
+	Type int
+	// Position and size of the bitmap, in pixels of the video.
+	X, Y int
+	W, H int
+	// Bitmap is W*H palette indices, row by row.
+	Bitmap []byte
+	// Palette colors are 0xAARRGGBB.
+	Palette []uint32
+	Text    string
+	Ass     string
+	Flags   int
+}
+
+// Subtitle is a decoded subtitle, copied from AVSubtitle, so it needs no Free.
+type Subtitle struct {
+	// Format is 0 for graphics and 1 for text subtitles.
+	Format int
+	// StartDisplayTime and EndDisplayTime are relative to Pts,
+	// the subtitle is displayed till the next one if EndDisplayTime is 0.
+	StartDisplayTime time.Duration
+	EndDisplayTime   time.Duration
+	// Pts is in AV_TIME_BASE units, AV_NOPTS_VALUE if unknown.
+	Pts   int64
+	Rects []*SubtitleRect
+}
+
+// Start returns the display start time in AV_TIME_BASE units.
+func (s *Subtitle) Start() int64 {
+	if s.Pts == AV_NOPTS_VALUE {
+		return AV_NOPTS_VALUE
+	}
+
+	return s.Pts + int64(s.StartDisplayTime/time.Microsecond)
+}
+
+// Duration returns the display duration, 0 if it is unknown.
+func (s *Subtitle) Duration() time.Duration {
+	if s.EndDisplayTime <= s.StartDisplayTime {
+		return 0
+	}
+
+	return s.EndDisplayTime - s.StartDisplayTime
+}
+
+func newSubtitle(sub *C.AVSubtitle) *Subtitle {
+	s := &Subtitle{
+		Format:           int(sub.format),
+		StartDisplayTime: time.Duration(sub.start_display_time) * time.Millisecond,
+		EndDisplayTime:   time.Duration(sub.end_display_time) * time.Millisecond,
+		Pts:              int64(sub.pts),
+		Rects:            make([]*SubtitleRect, 0, int(sub.num_rects)),
+	}
+
+	for i := 0; i < int(sub.num_rects); i++ {
+		r := C.gmf_sub_rect(sub, C.int(i))
+
+		rect := &SubtitleRect{
+			Type:  int(r._type),
+			X:     int(r.x),
+			Y:     int(r.y),
+			W:     int(r.w),
+			H:     int(r.h),
+			Flags: int(r.flags),
+		}
+
+		if r.text != nil {
+			rect.Text = C.GoString(r.text)
+		}
+
+		if r.ass != nil {
+			rect.Ass = C.GoString(r.ass)
+		}
+
+		if r.data[0] != nil && rect.W > 0 && rect.H > 0 {
+			linesize := int(r.linesize[0])
+			data := C.GoBytes(unsafe.Pointer(r.data[0]), C.int(linesize*rect.H))
+
+			rect.Bitmap = make([]byte, rect.W*rect.H)
+			for y := 0; y < rect.H; y++ {
+				copy(rect.Bitmap[y*rect.W:(y+1)*rect.W], data[y*linesize:])
+			}
+		}
+
+		if r.data[1] != nil {
+			rect.Palette = make([]uint32, int(r.nb_colors))
+			for j := range rect.Palette {
+				rect.Palette[j] = uint32(C.gmf_sub_rect_color(r, C.int(j)))
+			}
+		}
+
+		s.Rects = append(s.Rects, rect)
+	}
+
+	return s
+}
+
+// toAVSubtitle fills sub, which must be released by avsubtitle_free.
+func (s *Subtitle) toAVSubtitle(sub *C.AVSubtitle) error {
+	sub.format = C.uint16_t(s.Format)
+	sub.start_display_time = C.uint32_t(s.StartDisplayTime / time.Millisecond)
+	sub.end_display_time = C.uint32_t(s.EndDisplayTime / time.Millisecond)
+	sub.pts = C.int64_t(s.Pts)
+
+	if len(s.Rects) == 0 {
+		return nil
+	}
+
+	if ret := int(C.gmf_sub_alloc_rects(sub, C.int(len(s.Rects)))); ret < 0 {
+		return AvError(ret)
+	}
+
+	for i, rect := range s.Rects {
+		r := C.gmf_sub_rect(sub, C.int(i))
+
+		r._type = C.enum_AVSubtitleType(rect.Type)
+		r.x, r.y = C.int(rect.X), C.int(rect.Y)
+		r.w, r.h = C.int(rect.W), C.int(rect.H)
+		r.flags = C.int(rect.Flags)
+
+		var text, ass *C.char
+
+		if rect.Text != "" {
+			text = C.CString(rect.Text)
+		}
+
+		if rect.Ass != "" {
+			ass = C.CString(rect.Ass)
+		}
+
+		ret := int(C.gmf_sub_rect_text(r, text, ass))
+		C.free(unsafe.Pointer(text))
+		C.free(unsafe.Pointer(ass))
+
+		if ret < 0 {
+			return AvError(ret)
+		}
+
+		if rect.Type != SUBTITLE_BITMAP {
+			continue
+		}
+
+		if len(rect.Bitmap) < rect.W*rect.H {
+			return errors.New(fmt.Sprintf("Bitmap of rect %d is %d bytes, expected %dx%d", i, len(rect.Bitmap), rect.W, rect.H))
+		}
+
+		if len(rect.Palette) > 256 {
+			return errors.New(fmt.Sprintf("Palette of rect %d has %d colors, 256 is maximum", i, len(rect.Palette)))
+		}
+
+		var (
+			bitmap  *C.uint8_t
+			palette *C.uint32_t
+		)
+
+		if rect.W*rect.H > 0 {
+			bitmap = (*C.uint8_t)(unsafe.Pointer(&rect.Bitmap[0]))
+		}
+
+		if len(rect.Palette) > 0 {
+			palette = (*C.uint32_t)(unsafe.Pointer(&rect.Palette[0]))
+		}
+
+		if ret := int(C.gmf_sub_rect_bitmap(r, bitmap, palette, C.int(len(rect.Palette)))); ret < 0 {
+			return AvError(ret)
+		}
+	}
+
+	return nil
+}
+
+// DecodeSubtitle decodes a subtitle packet, nil packet flushes the decoder.
+// It returns nil without error, if the packet has no subtitle to display yet.
+// Pts of the subtitle is known, if pkt_timebase of the decoder is set,
+// which is so for Stream.CodecCtx.
+func (cc *CodecCtx) DecodeSubtitle(pkt *Packet) (*Subtitle, error) {
+	var (
+		sub    C.AVSubtitle
+		gotSub C.int
+	)
+
+	if pkt == nil {
+		pkt = NewPacket()
+		defer pkt.Free()
+	}
+
+	if ret := int(C.avcodec_decode_subtitle2(cc.avCodecCtx, &sub, &gotSub, &pkt.avPacket)); ret < 0 {
+		return nil, newAVError(ret, "Unable to decode subtitle", cc.Codec().Name())
+	}
+
+	if gotSub == 0 {
+		return nil, nil
+	}
+	defer C.avsubtitle_free(&sub)
+
+	return newSubtitle(&sub), nil
+}
+
+// EncodeSubtitle encodes the subtitle into a packet. Text encoders, e.g. mov_text
+// or subrip, accept SUBTITLE_ASS rects only and need the ASS header, which may
+// be copied from the decoder with SetSubtitleHeader before the encoder is opened.
+// Packet pts is the display start in time base of cc, duration is the display time.
+func (cc *CodecCtx) EncodeSubtitle(s *Subtitle) (*Packet, error) {
+	var sub C.AVSubtitle
+
+	defer C.avsubtitle_free(&sub)
+
+	if err := s.toAVSubtitle(&sub); err != nil {
+		return nil, err
+	}
+
+	// encoders expect the display to start at pts, as ffmpeg tool does
+	if sub.pts != C.int64_t(AV_NOPTS_VALUE) {
+		sub.pts += C.int64_t(s.StartDisplayTime / time.Microsecond)
+	}
+	if sub.end_display_time > sub.start_display_time {
+		sub.end_display_time -= sub.start_display_time
+	}
+	sub.start_display_time = 0
+
+	buf := (*C.uint8_t)(C.av_malloc(subtitleMaxSize))
+	if buf == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to allocate %d bytes for subtitle", subtitleMaxSize))
+	}
+	defer C.av_free(unsafe.Pointer(buf))
+
+	size := int(C.avcodec_encode_subtitle(cc.avCodecCtx, buf, subtitleMaxSize, &sub))
+	if size < 0 {
+		return nil, newAVError(size, "Unable to encode subtitle", cc.Codec().Name())
+	}
+
+	p := NewPacket()
+
+	if ret := int(C.av_new_packet(&p.avPacket, C.int(size))); ret < 0 {
+		return nil, AvError(ret)
+	}
+
+	C.memcpy(unsafe.Pointer(p.avPacket.data), unsafe.Pointer(buf), C.size_t(size))
+
+	p.avPacket.pts = C.int64_t(AV_NOPTS_VALUE)
+	if sub.pts != C.int64_t(AV_NOPTS_VALUE) {
+		p.avPacket.pts = C.av_rescale_q(sub.pts, C.AVRational{num: 1, den: C.AV_TIME_BASE}, cc.avCodecCtx.time_base)
+	}
+	p.avPacket.dts = p.avPacket.pts
+
+	if sub.end_display_time > 0 {
+		p.avPacket.duration = C.av_rescale_q(C.int64_t(sub.end_display_time), C.AVRational{num: 1, den: 1000}, cc.avCodecCtx.time_base)
+	}
+
+	p.avPacket.flags |= C.AV_PKT_FLAG_KEY
+
+	return p, nil
+}
+
+// SubtitleHeader returns the codec subtitle header, e.g. ASS [Script Info] and styles.
+func (cc *CodecCtx) SubtitleHeader() []byte {
+	if cc.avCodecCtx.subtitle_header == nil || cc.avCodecCtx.subtitle_header_size <= 0 {
+		return nil
+	}
+
+	return C.GoBytes(unsafe.Pointer(cc.avCodecCtx.subtitle_header), cc.avCodecCtx.subtitle_header_size)
+}
+
+// SetSubtitleHeader sets the codec subtitle header, it should be done before Open.
+func (cc *CodecCtx) SetSubtitleHeader(header []byte) error {
+	C.av_freep(unsafe.Pointer(&cc.avCodecCtx.subtitle_header))
+	cc.avCodecCtx.subtitle_header_size = 0
+
+	if len(header) == 0 {
+		return nil
+	}
+
+	// zero terminated, as text encoders parse it as a string
+	buf := (*C.uint8_t)(C.av_mallocz(C.size_t(len(header) + 1)))
+	if buf == nil {
+		return errors.New(fmt.Sprintf("Unable to allocate %d bytes for subtitle header", len(header)+1))
+	}
+
+	C.memcpy(unsafe.Pointer(buf), unsafe.Pointer(&header[0]), C.size_t(len(header)))
+
+	cc.avCodecCtx.subtitle_header = buf
+	cc.avCodecCtx.subtitle_header_size = C.int(len(header))
+
+	return nil
+}
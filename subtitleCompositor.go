@@ -0,0 +1,350 @@
+package gmf
+
+/*
+
+#cgo pkg-config: libavutil
+
+#include <math.h>
+#include <string.h>
+#include "libavutil/common.h"
+#include "libavutil/frame.h"
+#include "libavutil/mem.h"
+#include "libavutil/pixdesc.h"
+
+#ifdef AV_PIX_FMT_FLAG_FLOAT
+#define GMF_COMP_UNSUPPORTED (AV_PIX_FMT_FLAG_HWACCEL | AV_PIX_FMT_FLAG_PAL | AV_PIX_FMT_FLAG_BAYER | AV_PIX_FMT_FLAG_FLOAT)
+#else
+#define GMF_COMP_UNSUPPORTED (AV_PIX_FMT_FLAG_HWACCEL | AV_PIX_FMT_FLAG_PAL | AV_PIX_FMT_FLAG_BAYER)
+#endif
+
+static int gmf_comp_is_supported(int format) {
+	const AVPixFmtDescriptor *desc = av_pix_fmt_desc_get(format);
+
+	return desc && !(desc->flags & GMF_COMP_UNSUPPORTED);
+}
+
+// bilinear sample of ARGB image with premultiplied alpha, so transparent pixels don't bleed
+static uint32_t gmf_comp_sample(const uint32_t *src, int sw, int sh, double u, double v) {
+	double fx, fy, w[4], acc[4] = { 0 }, a;
+	uint32_t p[4];
+	int x0, y0, x1, y1, i;
+
+	u = av_clipd(u, 0, sw - 1);
+	v = av_clipd(v, 0, sh - 1);
+
+	x0 = (int)u;
+	y0 = (int)v;
+	x1 = FFMIN(x0 + 1, sw - 1);
+	y1 = FFMIN(y0 + 1, sh - 1);
+	fx = u - x0;
+	fy = v - y0;
+
+	p[0] = src[y0 * sw + x0];
+	p[1] = src[y0 * sw + x1];
+	p[2] = src[y1 * sw + x0];
+	p[3] = src[y1 * sw + x1];
+
+	w[0] = (1 - fx) * (1 - fy);
+	w[1] = fx * (1 - fy);
+	w[2] = (1 - fx) * fy;
+	w[3] = fx * fy;
+
+	for (i = 0; i < 4; i++) {
+		a = (p[i] >> 24) * w[i];
+		acc[0] += ((p[i] >> 16) & 0xff) * a;
+		acc[1] += ((p[i] >> 8) & 0xff) * a;
+		acc[2] += (p[i] & 0xff) * a;
+		acc[3] += a;
+	}
+
+	if (acc[3] < 0.5) {
+		return 0;
+	}
+
+	return (uint32_t)lrint(acc[3]) << 24 |
+		av_clip_uint8(lrint(acc[0] / acc[3])) << 16 |
+		av_clip_uint8(lrint(acc[1] / acc[3])) << 8 |
+		av_clip_uint8(lrint(acc[2] / acc[3]));
+}
+
+// gmf_comp_value returns the component c of ARGB color, normalized to [0, 1]
+static double gmf_comp_value(uint32_t p, int c, int is_rgb, int is_gray, int full_range, int bt709) {
+	double r = ((p >> 16) & 0xff) / 255.0, g = ((p >> 8) & 0xff) / 255.0, b = (p & 0xff) / 255.0;
+	double kr = bt709 ? 0.2126 : 0.299, kb = bt709 ? 0.0722 : 0.114;
+	double y = kr * r + (1 - kr - kb) * g + kb * b;
+
+	if (is_rgb) {
+		return c == 0 ? r : c == 1 ? g : b;
+	}
+
+	if (is_gray) {
+		return y;
+	}
+
+	switch (c) {
+	case 0:
+		return full_range ? y : (16 + 219 * y) / 255;
+	case 1:
+		return (128 + (full_range ? 255 : 224) * (b - y) / (2 * (1 - kb))) / 255;
+	}
+
+	return (128 + (full_range ? 255 : 224) * (r - y) / (2 * (1 - kr))) / 255;
+}
+
+// gmf_comp_blend scales ARGB image sw x sh to dw x dh and blends it onto the frame at dx, dy.
+// Chroma of subsampled formats is blended with the average of the covered pixels.
+static int gmf_comp_blend(AVFrame *frame, const uint32_t *src, int sw, int sh, int dx, int dy, int dw, int dh) {
+	const AVPixFmtDescriptor *desc = av_pix_fmt_desc_get(frame->format);
+	int x0 = FFMAX(dx, 0), y0 = FFMAX(dy, 0);
+	int x1 = FFMIN(dx + dw, frame->width), y1 = FFMIN(dy + dh, frame->height);
+	int w = x1 - x0, h = y1 - y0;
+	int is_rgb, is_gray, alpha, full_range, bt709;
+	int ret = 0, c, x, y;
+	uint32_t *canvas;
+	uint16_t *line;
+
+	if (!desc || (desc->flags & GMF_COMP_UNSUPPORTED)) {
+		return AVERROR(ENOSYS);
+	}
+
+	if (w <= 0 || h <= 0 || sw <= 0 || sh <= 0) {
+		return 0;
+	}
+
+	// not refcounted frame data is owned by the caller and is writable
+	if (frame->buf[0] && (ret = av_frame_make_writable(frame)) < 0) {
+		return ret;
+	}
+
+	canvas = av_malloc_array(w * h, sizeof(*canvas));
+	line = av_malloc_array(w + 1, sizeof(*line));
+	if (!canvas || !line) {
+		ret = AVERROR(ENOMEM);
+		goto end;
+	}
+
+	for (y = 0; y < h; y++) {
+		for (x = 0; x < w; x++) {
+			canvas[y * w + x] = gmf_comp_sample(src, sw, sh,
+				(x0 + x - dx + 0.5) * sw / dw - 0.5, (y0 + y - dy + 0.5) * sh / dh - 0.5);
+		}
+	}
+
+	is_rgb = !!(desc->flags & AV_PIX_FMT_FLAG_RGB);
+	is_gray = !is_rgb && desc->nb_components <= 2;
+	alpha = desc->flags & AV_PIX_FMT_FLAG_ALPHA ? desc->nb_components - 1 : -1;
+	full_range = frame->color_range == AVCOL_RANGE_JPEG || !strncmp(desc->name, "yuvj", 4);
+	bt709 = frame->colorspace == AVCOL_SPC_BT709;
+
+	for (c = 0; c < desc->nb_components; c++) {
+		int chroma = !is_rgb && !is_gray && (c == 1 || c == 2);
+		int ssw = chroma ? desc->log2_chroma_w : 0, ssh = chroma ? desc->log2_chroma_h : 0;
+		int px0 = x0 >> ssw, px1 = AV_CEIL_RSHIFT(x1, ssw);
+		int py0 = y0 >> ssh, py1 = AV_CEIL_RSHIFT(y1, ssh);
+		int max = (1 << desc->comp[c].depth) - 1;
+		int px, py, bx, by;
+
+		for (py = py0; py < py1; py++) {
+			av_read_image_line(line, (const uint8_t **)frame->data, frame->linesize, desc, px0, py, c, px1 - px0, 0);
+
+			for (px = px0; px < px1; px++) {
+				double n = 0, sa = 0, sv = 0;
+
+				// the frame pixels of the plane sample
+				for (by = py << ssh; by < FFMIN((py + 1) << ssh, frame->height); by++) {
+					for (bx = px << ssw; bx < FFMIN((px + 1) << ssw, frame->width); bx++) {
+						uint32_t p;
+
+						n++;
+
+						if (bx < x0 || bx >= x1 || by < y0 || by >= y1) {
+							continue;
+						}
+
+						p = canvas[(by - y0) * w + bx - x0];
+						sa += p >> 24;
+						sv += (p >> 24) * (c == alpha ? 1 : gmf_comp_value(p, c, is_rgb, is_gray, full_range, bt709));
+					}
+				}
+
+				if (sa > 0) {
+					line[px - px0] = lrint((sv * max + line[px - px0] * (255 * n - sa)) / (255 * n));
+				}
+			}
+
+			av_write_image_line(line, frame->data, frame->linesize, desc, px0, py, c, px1 - px0);
+		}
+	}
+
+end:
+	av_free(canvas);
+	av_free(line);
+
+	return ret;
+}
+
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unsafe"
+)
+
+// SubtitleCompositor renders bitmap subtitles, e.g. DVB, PGS or DVD, onto video frames.
+// Subtitles are displayed in their display window, a subtitle without EndDisplayTime
+// is displayed till the next one, so an empty subtitle clears the screen, as DVB
+// and PGS decoders produce. Text rects are ignored.
+type SubtitleCompositor struct {
+	cc     *CodecCtx
+	width  int
+	height int
+	subs   []*Subtitle
+}
+
+// NewSubtitleCompositor creates a compositor, cc is the opened subtitle decoder
+// for Decode, it may be nil, if subtitles are added with Add.
+func NewSubtitleCompositor(cc *CodecCtx) *SubtitleCompositor {
+	return &SubtitleCompositor{cc: cc, subs: make([]*Subtitle, 0)}
+}
+
+// SetCanvasSize sets the video size, which subtitle rects are positioned for.
+// By default it is the decoder size, if known, or the size of the frame,
+// rects are scaled from it to the frame size.
+func (c *SubtitleCompositor) SetCanvasSize(w, h int) *SubtitleCompositor {
+	c.width, c.height = w, h
+	return c
+}
+
+// Decode decodes the subtitle packet and adds the subtitle, if any.
+func (c *SubtitleCompositor) Decode(pkt *Packet) error {
+	if c.cc == nil {
+		return errors.New("Subtitle decoder is not set")
+	}
+
+	sub, err := c.cc.DecodeSubtitle(pkt)
+	if err != nil {
+		return err
+	}
+
+	if sub != nil {
+		c.Add(sub)
+	}
+
+	return nil
+}
+
+// Add adds the decoded subtitle, keeping subtitles ordered by display start.
+func (c *SubtitleCompositor) Add(sub *Subtitle) {
+	start := sub.Start()
+
+	i := sort.Search(len(c.subs), func(i int) bool {
+		return c.subs[i].Start() > start
+	})
+
+	c.subs = append(c.subs, nil)
+	copy(c.subs[i+1:], c.subs[i:])
+	c.subs[i] = sub
+}
+
+// Compose renders the subtitle displayed at the frame pts in timeBase onto the frame.
+func (c *SubtitleCompositor) Compose(frame *Frame, timeBase AVRational) error {
+	pts := frame.Pts()
+	if pts == AV_NOPTS_VALUE {
+		pts = frame.BestEffortTimestamp()
+	}
+
+	if pts == AV_NOPTS_VALUE {
+		return errors.New("Unable to compose subtitle onto frame without timestamp")
+	}
+
+	return c.ComposeAt(frame, RescaleQ(pts, timeBase, AV_TIME_BASE_Q))
+}
+
+// ComposeAt renders the subtitle displayed at t in AV_TIME_BASE units onto the frame.
+// Frames are expected in presentation order, subtitles before t are dropped.
+func (c *SubtitleCompositor) ComposeAt(frame *Frame, t int64) error {
+	if !c.IsSupported(int32(frame.Format())) {
+		return errors.New(fmt.Sprintf("Unable to compose subtitle onto pixel format %d", frame.Format()))
+	}
+
+	sub := c.active(t)
+	if sub == nil {
+		return nil
+	}
+
+	cw, ch := c.width, c.height
+	if (cw <= 0 || ch <= 0) && c.cc != nil {
+		cw, ch = c.cc.Width(), c.cc.Height()
+	}
+	if cw <= 0 || ch <= 0 {
+		cw, ch = frame.Width(), frame.Height()
+	}
+
+	fw, fh := frame.Width(), frame.Height()
+
+	for _, rect := range sub.Rects {
+		if rect.Type != SUBTITLE_BITMAP || rect.W <= 0 || rect.H <= 0 || len(rect.Bitmap) < rect.W*rect.H {
+			continue
+		}
+
+		argb := make([]uint32, rect.W*rect.H)
+		for i := range argb {
+			if idx := int(rect.Bitmap[i]); idx < len(rect.Palette) {
+				argb[i] = rect.Palette[idx]
+			}
+		}
+
+		x, y := rect.X*fw/cw, rect.Y*fh/ch
+		w, h := (rect.X+rect.W)*fw/cw-x, (rect.Y+rect.H)*fh/ch-y
+
+		if w <= 0 {
+			w = 1
+		}
+		if h <= 0 {
+			h = 1
+		}
+
+		if ret := int(C.gmf_comp_blend(frame.avFrame, (*C.uint32_t)(unsafe.Pointer(&argb[0])),
+			C.int(rect.W), C.int(rect.H), C.int(x), C.int(y), C.int(w), C.int(h))); ret < 0 {
+			return newAVError(ret, "Unable to compose subtitle", "")
+		}
+	}
+
+	return nil
+}
+
+// IsSupported reports whether subtitles can be composed onto frames of the pixel format.
+// Hardware, paletted, bayer and float formats are not supported.
+func (c *SubtitleCompositor) IsSupported(pixFmt int32) bool {
+	return C.gmf_comp_is_supported(C.int(pixFmt)) != 0
+}
+
+// active returns the subtitle displayed at t, dropping the subtitles replaced by it.
+func (c *SubtitleCompositor) active(t int64) *Subtitle {
+	idx := -1
+
+	for i, sub := range c.subs {
+		if start := sub.Start(); start != AV_NOPTS_VALUE && start > t {
+			break
+		}
+		idx = i
+	}
+
+	if idx < 0 {
+		return nil
+	}
+
+	c.subs = c.subs[idx:]
+
+	sub := c.subs[0]
+
+	if d := sub.Duration(); d > 0 && sub.Start() != AV_NOPTS_VALUE && t >= sub.Start()+int64(d/time.Microsecond) {
+		return nil
+	}
+
+	return sub
+}
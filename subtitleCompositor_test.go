@@ -0,0 +1,177 @@
+package gmf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSubtitleCompositor(t *testing.T) {
+	c := NewSubtitleCompositor(nil)
+
+	c.Add(&Subtitle{
+		Pts:            int64(time.Second / time.Microsecond),
+		EndDisplayTime: 500 * time.Millisecond,
+		Rects: []*SubtitleRect{{
+			Type:    SUBTITLE_BITMAP,
+			X:       8,
+			Y:       0,
+			W:       4,
+			H:       2,
+			Bitmap:  []byte{0, 1, 1, 1, 0, 1, 1, 1},
+			Palette: []uint32{0x00000000, 0xffffffff},
+		}},
+	})
+
+	times := []time.Duration{500 * time.Millisecond, 1200 * time.Millisecond, 1600 * time.Millisecond}
+	i := 0
+
+	for frame := range GenSyntVideoN(len(times), 64, 48, AV_PIX_FMT_YUV420P) {
+		luma := append([]byte{}, frame.GetRawAudioData(0)...)
+		chroma := append([]byte{}, frame.GetRawAudioData(1)...)
+
+		if err := c.ComposeAt(frame, int64(times[i]/time.Microsecond)); err != nil {
+			t.Fatal(err)
+		}
+
+		resultLuma, resultChroma := frame.GetRawAudioData(0), frame.GetRawAudioData(1)
+		frame.Free()
+
+		// displayed from 1s to 1.5s only
+		if i != 1 {
+			if !bytes.Equal(luma, resultLuma) || !bytes.Equal(chroma, resultChroma) {
+				t.Fatalf("Expected frame at %v unchanged\n", times[i])
+			}
+			i++
+			continue
+		}
+
+		// white is 235 in limited range
+		for x := 9; x < 12; x++ {
+			if resultLuma[x] != 235 {
+				t.Fatalf("Expected white luma at %d, %d got\n", x, resultLuma[x])
+			}
+		}
+
+		if resultLuma[8] != luma[8] || resultLuma[12] != luma[12] {
+			t.Fatalf("Expected transparent pixels unchanged, %d %d got\n", resultLuma[8], resultLuma[12])
+		}
+
+		if resultChroma[5] != 128 {
+			t.Fatalf("Expected neutral chroma of white, %d got\n", resultChroma[5])
+		}
+
+		i++
+	}
+
+	if i != len(times) {
+		t.Fatalf("Expected %d frames, %d got\n", len(times), i)
+	}
+}
+
+func TestSubtitleCompositorScale(t *testing.T) {
+	frame := NewFrame().SetWidth(64).SetHeight(48).SetFormat(AV_PIX_FMT_RGBA)
+	if err := frame.ImgAlloc(); err != nil {
+		t.Fatal(err)
+	}
+	defer frame.Free()
+
+	row := append([]byte{}, frame.GetRawAudioData(0)...)
+
+	// rect 2x1 at 2x0 of 32x24 canvas covers 4x2 at 4x0 of the frame
+	c := NewSubtitleCompositor(nil).SetCanvasSize(32, 24)
+
+	c.Add(&Subtitle{
+		Pts: AV_NOPTS_VALUE,
+		Rects: []*SubtitleRect{{
+			Type:    SUBTITLE_BITMAP,
+			X:       2,
+			W:       2,
+			H:       1,
+			Bitmap:  []byte{0, 0},
+			Palette: []uint32{0xffff0000},
+		}},
+	})
+
+	if err := c.ComposeAt(frame, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	result := frame.GetRawAudioData(0)
+
+	for x := 4; x < 8; x++ {
+		if px := result[x*4 : x*4+4]; !bytes.Equal(px, []byte{255, 0, 0, 255}) {
+			t.Fatalf("Expected opaque red at %d, %v got\n", x, px)
+		}
+	}
+
+	if !bytes.Equal(result[3*4:4*4], row[3*4:4*4]) || !bytes.Equal(result[8*4:9*4], row[8*4:9*4]) {
+		t.Fatalf("Expected pixels out of the rect unchanged\n")
+	}
+}
+
+func TestSubtitleCompositorDecode(t *testing.T) {
+	newCodecCtx := func(codec *Codec, err error) *CodecCtx {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cc := NewCodecCtx(codec)
+		if cc == nil {
+			t.Fatal("Unable to allocate codec context")
+		}
+
+		cc.SetTimeBase(AVR{1, 90000}).SetDimension(64, 48)
+
+		if err := cc.Open(nil); err != nil {
+			t.Fatal(err)
+		}
+
+		return cc
+	}
+
+	enc := newCodecCtx(FindEncoder("dvbsub"))
+	defer enc.Free()
+
+	dec := newCodecCtx(FindDecoder("dvbsub"))
+	defer dec.Free()
+
+	pkt, err := enc.EncodeSubtitle(&Subtitle{
+		EndDisplayTime: 2 * time.Second,
+		Pts:            AV_NOPTS_VALUE,
+		Rects: []*SubtitleRect{{
+			Type:    SUBTITLE_BITMAP,
+			W:       4,
+			H:       2,
+			Bitmap:  []byte{1, 1, 1, 1, 1, 1, 1, 1},
+			Palette: []uint32{0x00000000, 0xffffffff},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkt.Free()
+
+	c := NewSubtitleCompositor(dec).SetCanvasSize(64, 48)
+
+	if err := c.Decode(pkt); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := <-GenSyntVideoN(1, 64, 48, AV_PIX_FMT_YUV420P)
+	defer frame.Free()
+
+	if err := c.ComposeAt(frame, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for x, y := range frame.GetRawAudioData(0)[:4] {
+		if y < 200 {
+			t.Fatalf("Expected white subtitle at %d, luma %d got\n", x, y)
+		}
+	}
+
+	if c.IsSupported(AV_PIX_FMT_NONE) {
+		t.Fatalf("Expected unknown pixel format to be unsupported\n")
+	}
+}
@@ -0,0 +1,236 @@
+package gmf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSrt = `1
+00:00:01,000 --> 00:00:02,500
+Hello world
+
+2
+00:00:03,000 --> 00:00:04,000
+<i>Second</i> line
+`
+
+func decodeSubtitles(t *testing.T, ctx *FmtCtx) []*Subtitle {
+	ist := assert(ctx.GetStream(0)).(*Stream)
+	if !ist.IsSubtitle() {
+		t.Fatalf("Expected subtitle stream, type %d got\n", ist.Type())
+	}
+
+	result := make([]*Subtitle, 0)
+
+	it := ctx.Packets(0)
+	defer it.Close()
+
+	for it.Next() {
+		sub, err := ist.CodecCtx().DecodeSubtitle(it.Packet())
+		it.Packet().Free()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sub != nil {
+			result = append(result, sub)
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestSubtitleSrtToMovText(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gmf-subtitle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srtFilename, mp4Filename := dir+"/test.srt", dir+"/test.mp4"
+
+	if err := ioutil.WriteFile(srtFilename, []byte(testSrt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputCtx, err := NewInputCtx(srtFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	subs := decodeSubtitles(t, inputCtx)
+	if len(subs) != 2 {
+		t.Fatalf("Expected 2 subtitles, %d got\n", len(subs))
+	}
+
+	if sub := subs[0]; sub.Start() != int64(time.Second/time.Microsecond) || sub.Duration() != 1500*time.Millisecond {
+		t.Fatalf("Expected subtitle at 1s for 1.5s, %d for %v got\n", sub.Start(), sub.Duration())
+	}
+
+	if rect := subs[0].Rects[0]; rect.Type != SUBTITLE_ASS || !strings.HasSuffix(rect.Ass, "Hello world") {
+		t.Fatalf("Expected ASS rect 'Hello world', %+v got\n", rect)
+	}
+
+	codec, err := FindEncoder("mov_text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cc := NewCodecCtx(codec)
+	if cc == nil {
+		t.Fatal("Unable to allocate codec context")
+	}
+	defer cc.Free()
+
+	cc.SetTimeBase(AVR{1, 1000})
+
+	ist := assert(inputCtx.GetStream(0)).(*Stream)
+	if err := cc.SetSubtitleHeader(ist.CodecCtx().SubtitleHeader()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cc.Open(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	outputCtx, err := NewOutputCtx(mp4Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ost := outputCtx.NewStream(codec)
+	if ost == nil {
+		t.Fatal("Unable to create stream")
+	}
+	ost.DumpContexCodec(cc)
+	ost.SetTimeBase(AVR{1, 1000})
+
+	if err := outputCtx.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sub := range subs {
+		pkt, err := cc.EncodeSubtitle(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		RescaleTs(pkt, AVR{1, 1000}.AVRational(), ost.TimeBase())
+		pkt.SetStreamIndex(ost.Index())
+
+		err = outputCtx.WritePacket(pkt)
+		pkt.Free()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outputCtx.Free()
+
+	resultCtx, err := NewInputCtx(mp4Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resultCtx.Free()
+
+	if id := assert(resultCtx.GetStream(0)).(*Stream).CodecCtx().Id(); id != AV_CODEC_ID_MOV_TEXT {
+		t.Fatalf("Expected mov_text, codec id %d got\n", id)
+	}
+
+	result := decodeSubtitles(t, resultCtx)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 subtitles, %d got\n", len(result))
+	}
+
+	if rect := result[0].Rects[0]; !strings.HasSuffix(rect.Ass, "Hello world") {
+		t.Fatalf("Expected 'Hello world', %+v got\n", rect)
+	}
+
+	if start := result[1].Start(); start != int64(3*time.Second/time.Microsecond) {
+		t.Fatalf("Expected second subtitle at 3s, %d got\n", start)
+	}
+}
+
+func TestSubtitleBitmap(t *testing.T) {
+	src := &Subtitle{
+		EndDisplayTime: 2 * time.Second,
+		Pts:            AV_NOPTS_VALUE,
+		Rects: []*SubtitleRect{{
+			Type:    SUBTITLE_BITMAP,
+			X:       10,
+			Y:       20,
+			W:       8,
+			H:       2,
+			Bitmap:  []byte{0, 1, 1, 0, 2, 2, 3, 3, 1, 0, 0, 1, 3, 3, 2, 2},
+			Palette: []uint32{0x00000000, 0xffffffff, 0xff000000, 0xffff0000},
+		}},
+	}
+
+	newCodecCtx := func(codec *Codec, err error) *CodecCtx {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cc := NewCodecCtx(codec)
+		if cc == nil {
+			t.Fatal("Unable to allocate codec context")
+		}
+
+		cc.SetTimeBase(AVR{1, 90000}).SetDimension(720, 576)
+
+		if err := cc.Open(nil); err != nil {
+			t.Fatal(err)
+		}
+
+		return cc
+	}
+
+	enc := newCodecCtx(FindEncoder("dvbsub"))
+	defer enc.Free()
+
+	dec := newCodecCtx(FindDecoder("dvbsub"))
+	defer dec.Free()
+
+	pkt, err := enc.EncodeSubtitle(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pkt.Free()
+
+	sub, err := dec.DecodeSubtitle(pkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sub == nil || len(sub.Rects) != 1 {
+		t.Fatalf("Expected subtitle with 1 rect, %+v got\n", sub)
+	}
+
+	rect := sub.Rects[0]
+
+	if rect.Type != SUBTITLE_BITMAP || rect.X != 10 || rect.Y != 20 || rect.W != 8 || rect.H != 2 {
+		t.Fatalf("Expected 8x2 bitmap at 10x20, %+v got\n", rect)
+	}
+
+	if !bytes.Equal(rect.Bitmap, src.Rects[0].Bitmap) || len(rect.Palette) < 4 {
+		t.Fatalf("Expected the same bitmap, %v with %d colors got\n", rect.Bitmap, len(rect.Palette))
+	}
+
+	// transparent color stays transparent
+	if alpha := rect.Palette[0] >> 24; alpha != 0 {
+		t.Fatalf("Expected transparent color 0, alpha %d got\n", alpha)
+	}
+
+	if _, err := enc.EncodeSubtitle(&Subtitle{Rects: []*SubtitleRect{{Type: SUBTITLE_BITMAP, W: 4, H: 2}}}); err == nil {
+		t.Fatalf("Expected error for missing bitmap\n")
+	}
+}
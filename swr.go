@@ -27,14 +27,38 @@ import (
 
 type SwrCtx struct {
 	swrCtx   *C.struct_SwrContext
-	channels int
+	chLayout *ChannelLayout
 	format   int32
 }
 
+// NewSwrCtx creates resampler, channels and format are those of output frames.
+// Output frames have default layout for the number of channels.
 func NewSwrCtx(options []*Option, channels int, format int32) (*SwrCtx, error) {
+	layout := NewDefaultChannelLayout(channels)
+
+	ctx, err := NewSwrCtxWithLayout(options, layout, format)
+	layout.Free()
+
+	return ctx, err
+}
+
+// NewSwrCtxWithLayout creates resampler, layout and format are those of output frames.
+// Input and output layouts are set by "in_chlayout" and "out_chlayout" options, e.g.
+//
+//	[]*Option{{"in_chlayout", inLayout}, {"out_chlayout", outLayout}, ...}
+func NewSwrCtxWithLayout(options []*Option, layout *ChannelLayout, format int32) (*SwrCtx, error) {
+	if layout == nil {
+		return nil, fmt.Errorf("channel layout is not initialized")
+	}
+
+	chLayout, err := layout.Copy()
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := &SwrCtx{
 		swrCtx:   C.swr_alloc(),
-		channels: channels,
+		chLayout: chLayout,
 		format:   format,
 	}
 
@@ -43,6 +67,7 @@ func NewSwrCtx(options []*Option, channels int, format int32) (*SwrCtx, error) {
 	}
 
 	if ret := int(C.swr_init(ctx.swrCtx)); ret < 0 {
+		ctx.Free()
 		return nil, fmt.Errorf("error initializing swr context - %s", AvError(ret))
 	}
 
@@ -51,6 +76,11 @@ func NewSwrCtx(options []*Option, channels int, format int32) (*SwrCtx, error) {
 
 func (ctx *SwrCtx) Free() {
 	C.swr_free(&ctx.swrCtx)
+
+	if ctx.chLayout != nil {
+		ctx.chLayout.Free()
+		ctx.chLayout = nil
+	}
 }
 
 func (ctx *SwrCtx) Convert(input *Frame) (*Frame, error) {
@@ -59,7 +89,7 @@ func (ctx *SwrCtx) Convert(input *Frame) (*Frame, error) {
 		err error
 	)
 
-	if dst, err = NewAudioFrame(ctx.format, ctx.channels, input.NbSamples()); err != nil {
+	if dst, err = NewAudioFrameWithLayout(ctx.format, ctx.chLayout, input.NbSamples()); err != nil {
 		return nil, fmt.Errorf("error creating new audio frame - %s\n", err)
 	}
 
@@ -74,7 +104,7 @@ func (ctx *SwrCtx) Flush(nbSamples int) (*Frame, error) {
 		err error
 	)
 
-	if dst, err = NewAudioFrame(ctx.format, ctx.channels, nbSamples); err != nil {
+	if dst, err = NewAudioFrameWithLayout(ctx.format, ctx.chLayout, nbSamples); err != nil {
 		return nil, fmt.Errorf("error creating new audio frame - %s\n", err)
 	}
 
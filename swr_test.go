@@ -6,16 +6,19 @@ import (
 )
 
 func TestSwrInit(t *testing.T) {
+	layout := NewDefaultChannelLayout(2)
+	defer layout.Free()
+
 	options := []*Option{
-		{"in_channel_count", 2},
+		{"in_chlayout", layout},
 		{"in_sample_rate", 44100},
 		{"in_sample_fmt", AV_SAMPLE_FMT_S16},
-		{"out_channel_count", 2},
+		{"out_chlayout", layout},
 		{"out_sample_rate", 44100},
 		{"out_sample_fmt", AV_SAMPLE_FMT_S16},
 	}
 
-	swrCtx, err := NewSwrCtx(options, 1, AV_SAMPLE_FMT_S16)
+	swrCtx, err := NewSwrCtxWithLayout(options, layout, AV_SAMPLE_FMT_S16)
 	if err != nil {
 		t.Fatal(err)
 	}
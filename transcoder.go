@@ -0,0 +1,558 @@
+package gmf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TranscodeStream is output settings of the input stream.
+type TranscodeStream struct {
+	// InputIndex is the index of the input stream.
+	InputIndex int
+	// Copy remuxes packets without decoding, other settings are ignored.
+	Copy bool
+	// Codec is encoder name or id, default codec of the output format is used if nil.
+	Codec   interface{}
+	BitRate int
+	// Width and Height of the output video, the video is scaled automatically.
+	// Input size is used, if not set.
+	Width  int
+	Height int
+	// SampleRate and Channels of the output audio, the audio is resampled automatically.
+	// A rate and a layout supported by the encoder are chosen, if not set.
+	SampleRate int
+	Channels   int
+	// Filter is a filter graph description, e.g. "fps=10,hflip", with single input and output.
+	Filter string
+	// Configure is called before the encoder is opened, e.g. to set profile or gop size.
+	Configure func(cc *CodecCtx)
+}
+
+// TranscodeProgress is passed to the progress callback after every input packet.
+type TranscodeProgress struct {
+	// Time is the timestamp of the last written packet.
+	Time    time.Duration
+	Packets int
+	Written int
+}
+
+type TranscoderConfig struct {
+	// Streams of the output. If empty, all the audio and video streams of the input
+	// are transcoded to the default codecs of the output format.
+	Streams  []TranscodeStream
+	Progress func(p TranscodeProgress)
+	// MaxQueuedPackets limits packets kept in memory, till all the encoders are opened
+	// and the output header is written, like -max_muxing_queue_size of ffmpeg.
+	// DefaultMaxQueuedPackets is used, if not set.
+	MaxQueuedPackets int
+}
+
+const DefaultMaxQueuedPackets = 1024
+
+type transcodeStream struct {
+	ist     *Stream
+	ost     *Stream
+	copy    bool
+	dec     *CodecCtx
+	enc     *CodecCtx
+	fg      *FilterGraph
+	lastPts int64
+}
+
+// timeBase returns time base of the packets produced for the stream.
+func (st *transcodeStream) timeBase() AVRational {
+	if st.copy {
+		return st.ist.TimeBase()
+	}
+
+	return st.enc.TimeBase()
+}
+
+type queuedPacket struct {
+	st  *transcodeStream
+	pkt *Packet
+}
+
+// Transcoder decodes, filters, encodes and muxes streams of the input to the output.
+// Input and output contexts are owned by caller, the output header and trailer are
+// written by the transcoder.
+//
+// E.g.:
+//
+//	t, err := NewTranscoder(inputCtx, outputCtx, TranscoderConfig{
+//		Streams: []TranscodeStream{
+//			{InputIndex: 0, Codec: "libx264", Width: 640, Height: 360},
+//			{InputIndex: 1, Copy: true},
+//		},
+//	})
+//	...
+//	defer t.Free()
+//
+//	if err := t.Run(); err != nil {
+//		...
+//	}
+type Transcoder struct {
+	input         *FmtCtx
+	output        *FmtCtx
+	streams       map[int]*transcodeStream
+	order         []*transcodeStream
+	progress      func(p TranscodeProgress)
+	stat          TranscodeProgress
+	headerWritten bool
+	// packets encoded before all the encoders are opened
+	queue    []queuedPacket
+	maxQueue int
+}
+
+func NewTranscoder(input, output *FmtCtx, config TranscoderConfig) (*Transcoder, error) {
+	t := &Transcoder{
+		input:    input,
+		output:   output,
+		streams:  make(map[int]*transcodeStream),
+		order:    make([]*transcodeStream, 0),
+		progress: config.Progress,
+		maxQueue: config.MaxQueuedPackets,
+	}
+
+	if t.maxQueue <= 0 {
+		t.maxQueue = DefaultMaxQueuedPackets
+	}
+
+	settings := config.Streams
+
+	if len(settings) == 0 {
+		for i := 0; i < input.StreamsCnt(); i++ {
+			if ist, err := input.GetStream(i); err == nil && (ist.IsVideo() || ist.IsAudio()) && !ist.IsAttachedPic() {
+				settings = append(settings, TranscodeStream{InputIndex: i})
+			}
+		}
+	}
+
+	if len(settings) == 0 {
+		return nil, errors.New("No streams to transcode")
+	}
+
+	for _, s := range settings {
+		if _, ok := t.streams[s.InputIndex]; ok {
+			t.Free()
+			return nil, errors.New(fmt.Sprintf("Input stream %d is mapped twice", s.InputIndex))
+		}
+
+		// the stream is registered even on error, so its encoder is freed
+		st, err := t.addStream(s)
+		if st != nil {
+			t.streams[s.InputIndex] = st
+			t.order = append(t.order, st)
+		}
+		if err != nil {
+			t.Free()
+			return nil, err
+		}
+	}
+
+	// unused streams aren't demuxed at all
+	for i := 0; i < input.StreamsCnt(); i++ {
+		if _, ok := t.streams[i]; !ok {
+			if ist, err := input.GetStream(i); err == nil {
+				ist.SetDiscard(AVDISCARD_ALL)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+func (t *Transcoder) addStream(s TranscodeStream) (*transcodeStream, error) {
+	ist, err := t.input.GetStream(s.InputIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &transcodeStream{ist: ist, copy: s.Copy, lastPts: AV_NOPTS_VALUE}
+
+	if s.Copy {
+		if st.ost = t.output.NewStream(nil); st.ost == nil {
+			return nil, errors.New(fmt.Sprintf("Unable to create output stream for input stream %d", s.InputIndex))
+		}
+
+		if err := st.ost.CopyCodecPar(ist.GetCodecPar()); err != nil {
+			return nil, err
+		}
+
+		st.ost.avStream.codecpar.codec_tag = 0
+		st.ost.SetTimeBase(ist.TimeBase().AVR())
+		st.ost.SetDisposition(ist.Disposition())
+
+		return st, st.ost.CopyMetadata(ist)
+	}
+
+	if !ist.IsVideo() && !ist.IsAudio() {
+		return nil, errors.New(fmt.Sprintf("Input stream %d can't be transcoded, only audio and video are supported", s.InputIndex))
+	}
+
+	codecId := s.Codec
+	if codecId == nil {
+		if codecId, err = t.output.GuessEncodeCodecId(ist.Type()); err != nil {
+			return nil, err
+		}
+	}
+
+	codec, err := FindEncoder(codecId)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.dec = ist.CodecCtx(); st.dec == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to open decoder of input stream %d", s.InputIndex))
+	}
+
+	if st.enc = NewCodecCtx(codec); st.enc == nil {
+		return nil, errors.New("Unable to allocate codec context")
+	}
+
+	if s.BitRate > 0 {
+		st.enc.SetBitRate(s.BitRate)
+	}
+
+	if s.Width > 0 && s.Height > 0 {
+		st.enc.SetDimension(s.Width, s.Height)
+	}
+
+	if s.SampleRate > 0 {
+		st.enc.SetSampleRate(s.SampleRate)
+	}
+
+	if s.Channels > 0 {
+		st.enc.SetChannels(s.Channels)
+	}
+
+	if t.output.IsGlobalHeader() {
+		st.enc.SetFlag(CODEC_FLAG_GLOBAL_HEADER)
+	}
+
+	if codec.IsExperimental() {
+		st.enc.SetStrictCompliance(FF_COMPLIANCE_EXPERIMENTAL)
+	}
+
+	if s.Configure != nil {
+		s.Configure(st.enc)
+	}
+
+	if st.ost = t.output.NewStream(codec); st.ost == nil {
+		return st, errors.New(fmt.Sprintf("Unable to create output stream for input stream %d", s.InputIndex))
+	}
+
+	st.ost.SetCodecCtx(st.enc)
+
+	if st.fg, err = NewGraph(s.Filter, ist.Type(), []*Stream{ist}, []*Stream{st.ost}, nil); err != nil {
+		return st, err
+	}
+
+	return st, st.ost.CopyMetadata(ist)
+}
+
+// Run transcodes the input up to the end.
+func (t *Transcoder) Run() error {
+	return t.RunWithContext(context.Background())
+}
+
+// RunWithContext is the same as Run, it returns ctx.Err() as soon as ctx is done.
+// The output trailer isn't written in this case.
+func (t *Transcoder) RunWithContext(ctx context.Context) error {
+	// header of copy only output is written right away
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+
+	for {
+		pkt, err := t.input.ReadPacket(ctx)
+		if isEOF(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		t.stat.Packets++
+
+		st, ok := t.streams[pkt.StreamIndex()]
+		if !ok {
+			pkt.Free()
+			continue
+		}
+
+		if err := t.processPacket(st, pkt); err != nil {
+			return err
+		}
+
+		if t.progress != nil {
+			t.progress(t.stat)
+		}
+	}
+
+	return t.flush()
+}
+
+func (t *Transcoder) processPacket(st *transcodeStream, pkt *Packet) error {
+	if st.copy {
+		pkt.avPacket.pos = -1
+		return t.writePacket(st, pkt)
+	}
+
+	frames, err := st.dec.Decode(pkt)
+	pkt.Free()
+
+	// broken packets are skipped like ffmpeg does
+	if isAVError(err, AVERROR_INVALIDDATA) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return t.filterFrames(st, frames)
+}
+
+func (t *Transcoder) filterFrames(st *transcodeStream, frames []*Frame) error {
+	for i, frame := range frames {
+		ts := frame.BestEffortTimestamp()
+
+		// audio buffer source time base is 1/sample_rate
+		if st.ist.IsAudio() && ts != AV_NOPTS_VALUE {
+			ts = RescaleQ(ts, st.ist.TimeBase(), AVR{Num: 1, Den: int(frame.SampleRate())}.AVRational())
+		}
+
+		frame.SetPts(ts)
+
+		err := st.fg.AddFrame(frame, 0, AV_BUFFERSRC_FLAG_KEEP_REF)
+		frame.Free()
+		if err != nil {
+			freeFrames(frames[i+1:])
+			return err
+		}
+	}
+
+	if len(st.fg.outFilterCtxs) == 0 {
+		// no frames yet, the graph isn't configured
+		return nil
+	}
+
+	// the encoder is configured by the graph output
+	if err := st.fg.initEncoderContext(0); err != nil {
+		return err
+	}
+
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+
+	return t.receiveFrames(st)
+}
+
+func (t *Transcoder) receiveFrames(st *transcodeStream) error {
+	for {
+		frame, err := st.fg.receiveFrame()
+		if isAVError(err, AVERROR_EAGAIN) || isEOF(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := t.encodeFrame(st, frame); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *Transcoder) encodeFrame(st *transcodeStream, frame *Frame) error {
+	if pts := frame.Pts(); pts != AV_NOPTS_VALUE {
+		pts = RescaleQ(pts, st.fg.sinkTimeBase(), st.enc.TimeBase())
+
+		// the encoder requires monotonic timestamps
+		if st.ist.IsVideo() && st.lastPts != AV_NOPTS_VALUE && pts <= st.lastPts {
+			frame.Free()
+			return nil
+		}
+
+		frame.SetPts(pts)
+		st.lastPts = pts
+	}
+
+	pkts, err := st.enc.Encode([]*Frame{frame}, -1)
+	if err != nil {
+		return err
+	}
+
+	return t.writePackets(st, pkts)
+}
+
+func (t *Transcoder) writePackets(st *transcodeStream, pkts []*Packet) error {
+	for i, pkt := range pkts {
+		if err := t.writePacket(st, pkt); err != nil {
+			freePackets(pkts[i+1:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePacket writes packet of the stream time base, pkt is freed.
+func (t *Transcoder) writePacket(st *transcodeStream, pkt *Packet) error {
+	pkt.SetStreamIndex(st.ost.Index())
+
+	if !t.headerWritten {
+		if len(t.queue) >= t.maxQueue {
+			pkt.Free()
+			return errors.New(fmt.Sprintf("Too many packets queued, %d, before all the encoders are opened", len(t.queue)))
+		}
+
+		t.queue = append(t.queue, queuedPacket{st: st, pkt: pkt})
+		return nil
+	}
+
+	RescaleTs(pkt, st.timeBase(), st.ost.TimeBase())
+
+	if dts := pkt.Dts(); dts != AV_NOPTS_VALUE {
+		if ts := time.Duration(RescaleQ(dts, st.ost.TimeBase(), AV_TIME_BASE_Q)) * time.Microsecond; ts > t.stat.Time {
+			t.stat.Time = ts
+		}
+	}
+
+	err := t.output.WritePacket(pkt)
+	pkt.Free()
+	if err != nil {
+		return err
+	}
+
+	t.stat.Written++
+
+	return nil
+}
+
+// writeHeader writes the output header as soon as all the encoders are opened,
+// then packets queued before are written.
+func (t *Transcoder) writeHeader() error {
+	if t.headerWritten {
+		return nil
+	}
+
+	for _, st := range t.order {
+		if !st.copy && !st.enc.IsOpen() {
+			return nil
+		}
+	}
+
+	for _, st := range t.order {
+		if !st.copy {
+			st.ost.SetTimeBase(st.enc.TimeBase().AVR())
+		}
+	}
+
+	if err := t.output.WriteHeader(); err != nil {
+		return err
+	}
+
+	t.headerWritten = true
+
+	queue := t.queue
+	t.queue = nil
+
+	for i, q := range queue {
+		if err := t.writePacket(q.st, q.pkt); err != nil {
+			for _, rest := range queue[i+1:] {
+				rest.pkt.Free()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush drains decoders, filters and encoders, then writes the trailer.
+func (t *Transcoder) flush() error {
+	for _, st := range t.order {
+		if st.copy {
+			continue
+		}
+
+		frames, err := st.dec.Decode(nil)
+		if err != nil && !isEOF(err) {
+			return err
+		}
+
+		if err := t.filterFrames(st, frames); err != nil {
+			return err
+		}
+
+		if len(st.fg.inFilterCtxs) == 0 {
+			return errors.New(fmt.Sprintf("No frames decoded from input stream %d", st.ist.Index()))
+		}
+
+		if err := st.fg.Close(0); err != nil {
+			return err
+		}
+
+		if err := t.receiveFrames(st); err != nil {
+			return err
+		}
+	}
+
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+
+	if !t.headerWritten {
+		return errors.New("Unable to write header, not all the encoders are opened")
+	}
+
+	for _, st := range t.order {
+		if st.copy {
+			continue
+		}
+
+		pkts, err := st.enc.Encode(nil, 0)
+		if err != nil && !isEOF(err) {
+			return err
+		}
+
+		if err := t.writePackets(st, pkts); err != nil {
+			return err
+		}
+	}
+
+	if t.progress != nil {
+		t.progress(t.stat)
+	}
+
+	return t.output.WriteTrailer()
+}
+
+// Free releases filter graphs and encoders, input and output contexts should be freed by caller.
+func (t *Transcoder) Free() {
+	for _, q := range t.queue {
+		q.pkt.Free()
+	}
+	t.queue = nil
+
+	for _, st := range t.order {
+		if st.fg != nil {
+			st.fg.Release()
+		}
+		if st.enc != nil {
+			st.enc.Free()
+		}
+	}
+}
+
+func freeFrames(frames []*Frame) {
+	for _, f := range frames {
+		if f != nil {
+			f.Free()
+		}
+	}
+}
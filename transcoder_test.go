@@ -0,0 +1,116 @@
+package gmf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func transcodeSample(t *testing.T, config TranscoderConfig) *FmtCtx {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	buf := new(bytes.Buffer)
+
+	outputCtx, err := NewOutputCtxToWriter(buf, "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTranscoder(inputCtx, outputCtx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tr.Run()
+	tr.Free()
+	outputCtx.Free()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resultCtx, err := NewInputCtxFromReadSeeker(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resultCtx
+}
+
+func TestTranscoder(t *testing.T) {
+	var last TranscodeProgress
+
+	resultCtx := transcodeSample(t, TranscoderConfig{
+		Streams: []TranscodeStream{
+			{InputIndex: 0, Codec: "mpeg4", Width: 160, Height: 100, Filter: "hflip"},
+		},
+		Progress: func(p TranscodeProgress) {
+			if p.Packets < last.Packets || p.Time < last.Time {
+				t.Fatalf("Expected monotonic progress, %+v after %+v got\n", p, last)
+			}
+			last = p
+		},
+	})
+	defer resultCtx.Free()
+
+	if last.Packets != 25 || last.Written != 25 {
+		t.Fatalf("Expected 25 read and written packets, %+v got\n", last)
+	}
+
+	ost := assert(resultCtx.GetStream(0)).(*Stream)
+
+	if par := ost.GetCodecPar(); par.GetWidth() != 160 || par.GetHeight() != 100 || par.GetCodecId() != AV_CODEC_ID_MPEG4 {
+		t.Fatalf("Expected mpeg4 160x100, codec %d %dx%d got\n", par.GetCodecId(), par.GetWidth(), par.GetHeight())
+	}
+
+	if cnt := countPackets(t, resultCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestTranscoderCopy(t *testing.T) {
+	resultCtx := transcodeSample(t, TranscoderConfig{
+		Streams: []TranscodeStream{{InputIndex: 0, Copy: true}},
+		// the header is written right away, packets aren't queued
+		MaxQueuedPackets: 1,
+		Progress: func(p TranscodeProgress) {
+			if p.Written != p.Packets {
+				t.Fatalf("Expected all the packets written, %+v got\n", p)
+			}
+		},
+	})
+	defer resultCtx.Free()
+
+	ost := assert(resultCtx.GetStream(0)).(*Stream)
+
+	if par := ost.GetCodecPar(); par.GetWidth() != 320 || par.GetHeight() != 200 {
+		t.Fatalf("Expected 320x200, %dx%d got\n", par.GetWidth(), par.GetHeight())
+	}
+
+	if cnt := countPackets(t, resultCtx); cnt != 25 {
+		t.Fatalf("Expected %d packets, obtained %d\n", 25, cnt)
+	}
+}
+
+func TestTranscoderInvalidStream(t *testing.T) {
+	inputCtx, err := NewInputCtx(inputSampleFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inputCtx.Free()
+
+	outputCtx, err := NewOutputCtxToWriter(new(bytes.Buffer), "matroska")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputCtx.Free()
+
+	if _, err := NewTranscoder(inputCtx, outputCtx, TranscoderConfig{
+		Streams: []TranscodeStream{{InputIndex: 5}},
+	}); err == nil {
+		t.Fatalf("Expected error for not existing input stream\n")
+	}
+}
@@ -25,7 +25,6 @@ import "C"
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"syscall"
 	"unsafe"
@@ -39,7 +38,8 @@ type AVR struct {
 }
 
 const (
-	AVERROR_EOF = -541478725
+	AVERROR_EOF  = -541478725
+	AVERROR_EXIT = -1414092869
 	// AV_ROUND_PASS_MINMAX = 8192
 )
 
@@ -68,13 +68,18 @@ func (a AVRational) AVR() AVR {
 	return AVR{Num: int(a.num), Den: int(a.den)}
 }
 
+// AvError returns *AVError for the FFmpeg error code.
 func AvError(averr int) error {
+	return &AVError{Code: averr}
+}
+
+func avStrError(averr int) string {
 	errlen := 1024
 	b := make([]byte, errlen)
 
 	C.av_strerror(C.int(averr), (*C.char)(unsafe.Pointer(&b[0])), C.size_t(errlen))
 
-	return errors.New(string(b[:bytes.Index(b, []byte{0})]))
+	return string(b[:bytes.Index(b, []byte{0})])
 }
 
 func AvErrno(ret int) syscall.Errno {
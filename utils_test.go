@@ -1,6 +1,8 @@
 package gmf
 
 import (
+	"io"
+	"syscall"
 	"testing"
 )
 
@@ -9,3 +11,44 @@ func TestAvError(t *testing.T) {
 		t.Fatalf("Expected error is 'No such file or directory', '%s' got\n", err.Error())
 	}
 }
+
+func TestAvErrorIs(t *testing.T) {
+	cases := map[int]error{
+		AVERROR_EOF:                ErrEOF,
+		AVERROR_EAGAIN:             ErrAgain,
+		AVERROR_INVALIDDATA:        ErrInvalidData,
+		AVERROR_DECODER_NOT_FOUND:  ErrDecoderNotFound,
+		AVERROR_PROTOCOL_NOT_FOUND: ErrProtocolNotFound,
+	}
+
+	for code, expected := range cases {
+		if err := AvError(code); !err.(*AVError).Is(expected) {
+			t.Fatalf("Expected '%v' to match '%v'\n", err, expected)
+		}
+	}
+
+	if !ErrEOF.(*AVError).Is(io.EOF) {
+		t.Fatal("Expected ErrEOF to match io.EOF")
+	}
+
+	if ErrAgain.(*AVError).Is(io.EOF) {
+		t.Fatal("Unexpected ErrAgain to match io.EOF")
+	}
+}
+
+func TestAVErrorAs(t *testing.T) {
+	_, err := NewInputCtx("not-existing-file.mp4")
+
+	averr, ok := err.(*AVError)
+	if !ok {
+		t.Fatalf("Expected *AVError, '%T' got\n", err)
+	}
+
+	if averr.URL != "not-existing-file.mp4" {
+		t.Fatalf("Expected url 'not-existing-file.mp4', '%s' got\n", averr.URL)
+	}
+
+	if !averr.Is(syscall.ENOENT) {
+		t.Fatalf("Expected '%v' to match ENOENT\n", err)
+	}
+}